@@ -8,6 +8,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -15,19 +16,32 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/sergi/go-diff/diffmatchpatch"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 	"golang.org/x/crypto/argon2"
 )
 
+// notes with more runes than this are diffed line by line, see GetContentDiff
+const diffLineModeThreshold = 100000
+
 type Diff struct {
-	DiffString  string                `json:"diffString"`
-	DiffsObject []diffmatchpatch.Diff `json:"diffsObject"`
-	Edit        int                   `json:"edit"`
-	Add         int                   `json:"add"`
-	Remove      int                   `json:"remove"`
+	DiffString   string                `json:"diffString"`
+	DiffsObject  []diffmatchpatch.Diff `json:"diffsObject"`
+	LineMode     bool                  `json:"lineMode"` // DiffsObject holds whole lines
+	Edit         int                   `json:"edit"`     // runes
+	Add          int                   `json:"add"`
+	Remove       int                   `json:"remove"`
+	LinesAdded   int                   `json:"linesAdded"`
+	LinesRemoved int                   `json:"linesRemoved"`
+	LinesChanged int                   `json:"linesChanged"`
+	WordsAdded   int                   `json:"wordsAdded"`
+	WordsRemoved int                   `json:"wordsRemoved"`
 }
 
 type FileItem struct {
@@ -35,6 +49,10 @@ type FileItem struct {
 	Path     string      `json:"path"`
 	IsDir    bool        `json:"isDir"`
 	Children []*FileItem `json:"children,omitempty"`
+	// sync-tool conflict copies, see splitConflictCopy
+	ConflictTool string      `json:"conflictTool,omitempty"` // set on a conflict copy
+	Conflicts    []*FileItem `json:"conflicts,omitempty"`    // conflict copies grouped with their original
+	Meta         *NoteMeta   `json:"meta,omitempty"`         // front matter properties, from the search index
 }
 
 type Config struct {
@@ -47,15 +65,45 @@ type Config struct {
 	PrivacyMode      bool     `json:"privacyMode"`
 	Check            []byte   `json:"check"`
 	NonceCheck       []byte   `json:"nonceCheck"`
+	GitAutoCommit    bool     `json:"gitAutoCommit"`
+	GitCommitDelay   int      `json:"gitCommitDelay"`   // seconds
+	GitCommitMessage string   `json:"gitCommitMessage"` // template, see formatGitCommitMessage
 }
 
 type SearchResult struct {
-	Path        string `json:"path"`
-	Name        string `json:"name"`
-	IsDir       bool   `json:"isDir"`
-	MatchType   string `json:"matchType"`   // "filename", "foldername", "content"
-	MatchText   string `json:"matchText"`   // The actual matched text for content matches
-	ContextText string `json:"contextText"` // Surrounding context for content matches
+	Path        string        `json:"path"`
+	Name        string        `json:"name"`
+	IsDir       bool          `json:"isDir"`
+	MatchType   string        `json:"matchType"`         // "filename", "foldername", "content"
+	MatchText   string        `json:"matchText"`         // The actual matched text for content matches
+	ContextText string        `json:"contextText"`       // Surrounding context for content matches
+	Matches     []SearchMatch `json:"matches,omitempty"` // every occurrence for content matches
+	Score       int           `json:"score"`             // higher is better: fuzzy score of names, number of matches in content
+	Meta        *NoteMeta     `json:"meta,omitempty"`    // front matter properties of the note
+}
+
+// SearchMatch is one occurrence of the query in a note
+type SearchMatch struct {
+	Line      int    `json:"line"`    // 1-based
+	Column    int    `json:"column"`  // 1-based, in runes
+	Offset    int    `json:"offset"`  // byte offset of the match in the content
+	Length    int    `json:"length"`  // in bytes
+	Snippet   string `json:"snippet"` // the line of the match, cut around it when long
+	Start     int    `json:"start"`   // byte offsets of the highlight in the snippet
+	End       int    `json:"end"`
+	RuneStart int    `json:"runeStart"` // rune offsets of the highlight in the snippet
+	RuneEnd   int    `json:"runeEnd"`
+	Element   string `json:"element"` // markdown element the match starts in: "code", "heading", "link", "text" or "frontmatter"
+}
+
+// VaultPathError is returned when a path given to a file operation resolves outside of the opened vault
+type VaultPathError struct {
+	Path string
+	Root string
+}
+
+func (e *VaultPathError) Error() string {
+	return "path_outside_vault"
 }
 
 type PathPart struct {
@@ -75,6 +123,28 @@ type App struct {
 	masterkey        []byte
 	cryptVersionMDE1 string
 	os               string
+	git              gitAutoCommit
+	security         securityCache
+	searchMu         sync.Mutex // guards search
+	searchLoad       sync.Mutex // one index load at a time, see getSearchIndex
+	searchPreload    sync.WaitGroup
+	search           *searchIndex
+	searchRun        searchRun
+	searchStream     atomic.Int64                        // id of the last streamed search
+	vaultsPath       string                              // list of the folders opened through OpenDirectoryDialog, see vaultsFile
+	emit             func(name string, data interface{}) // replaces runtime events, eg: in tests
+}
+
+// searchRun holds the cancellation of the search in progress, a newer search supersedes it
+type searchRun struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+// securityCache avoids reading tape.json on every HasSecurity call, entries are dropped by SaveConfig
+type securityCache struct {
+	mu     sync.Mutex
+	byRoot map[string]bool
 }
 
 // NewApp creates a new App application struct
@@ -91,7 +161,8 @@ func (a *App) startup(ctx context.Context) {
 }
 
 func (a *App) shutdown(ctx context.Context) {
-	//
+	a.flushGitCommit()
+	a.closeSearchIndex()
 }
 
 /**
@@ -259,16 +330,48 @@ func (a *App) SetupPassword(password string, rootPath string) string {
 }
 
 // check if the user has the right setup to encrypt notes
+// false when the config can't be read, writes use securityOf to fail instead of writing plain text
 func (a *App) HasSecurity(rootPath string) bool {
-	hasPrivacyOn := a.getPrivacyMode(rootPath)
-	_, check, nonceCheck := a.getCryptoOptions(rootPath)
-	return hasPrivacyOn && len(check) > 0 && len(nonceCheck) > 0
+	hasSecurity, _ := a.securityOf(rootPath)
+	return hasSecurity
+}
+
+// securityOf reports if a vault has privacy mode set up, cached by resolved root
+// a config that can't be read is an error and isn't cached, eg: while tape.json is being written
+func (a *App) securityOf(rootPath string) (bool, error) {
+	rootPath, err := a.resolveVaultPath(rootPath)
+	if err != nil {
+		return false, err
+	}
+	rootPath, err = evalPathSymlinks(rootPath)
+	if err != nil {
+		return false, err
+	}
+
+	a.security.mu.Lock()
+	defer a.security.mu.Unlock()
+
+	if hasSecurity, ok := a.security.byRoot[rootPath]; ok {
+		return hasSecurity, nil
+	}
+
+	hasPrivacyOn, check, nonceCheck, err := a.getCryptoOptions(rootPath)
+	if err != nil {
+		return false, err
+	}
+	hasSecurity := hasPrivacyOn && len(check) > 0 && len(nonceCheck) > 0
+
+	if a.security.byRoot == nil {
+		a.security.byRoot = make(map[string]bool)
+	}
+	a.security.byRoot[rootPath] = hasSecurity
+	return hasSecurity, nil
 }
 
 // PasswordIsCorrect check if the given password is correct comparing with the check data in the config
 func (a *App) PasswordIsCorrect(password string, rootPath string) bool {
 	if a.HasSecurity(rootPath) {
-		_, check, nonceCheck := a.getCryptoOptions(rootPath)
+		_, check, nonceCheck, _ := a.getCryptoOptions(rootPath)
 
 		candidateKey := deriveKey(password)
 
@@ -302,6 +405,10 @@ func (a *App) transformTreeIntoMDE1(password, rootPath string, nameFunc func(int
 	// set the rootPath for later use by file/folder func
 	a.rootPath = rootPath
 
+	// the index holds plain names and terms, it is rebuilt encrypted on next search
+	a.closeSearchIndex()
+	os.Remove(a.getSearchIndexPath())
+
 	// create the save directory
 	// we check in the walk to not process it
 	// we want it there because we want to fail if an already created backup folder exist
@@ -317,6 +424,20 @@ func (a *App) transformTreeIntoMDE1(password, rootPath string, nameFunc func(int
 		return fmt.Errorf("failed to create backup directory: %v", err)
 	}
 
+	// the history holds plain names and contents, it is moved along the original notes
+	historyDir := filepath.Join(rootPath, ".tape", "history")
+	if a.IsFileExists(historyDir) {
+		saveHistoryDir := filepath.Join(rootPath, backupDirName, ".tape", "history")
+		err = os.MkdirAll(filepath.Dir(saveHistoryDir), 0700)
+		if err != nil {
+			return err
+		}
+		err = os.Rename(historyDir, saveHistoryDir)
+		if err != nil {
+			return err
+		}
+	}
+
 	var nodes []PathPart
 	i := 0 // index for the walk
 
@@ -478,54 +599,287 @@ func (a *App) transformTreeIntoMDE1Test(password, rootPath string) error {
 /**
  * --- Diff
  */
+// countRunChanges returns the edited, added and removed amounts of a diff
+// a run of consecutive deletes and inserts is a replacement: the overlapping part is an edit,
+// the rest an addition or a removal. eg: abcd => xcd is 1 edit (a => x) and 1 removal (b)
+// size gives the amount of a diff text (runes, lines, words...)
+func countRunChanges(diffs []diffmatchpatch.Diff, size func(string) int) (edit, add, remove int) {
+	deleted, inserted := 0, 0
+	flush := func() {
+		changed := min(deleted, inserted)
+		edit += changed
+		add += inserted - changed
+		remove += deleted - changed
+		deleted, inserted = 0, 0
+	}
+
+	for _, diff := range diffs {
+		switch diff.Type {
+		case diffmatchpatch.DiffDelete:
+			deleted += size(diff.Text)
+		case diffmatchpatch.DiffInsert:
+			inserted += size(diff.Text)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return edit, add, remove
+}
+
+// diffTokens diffs two lists of tokens (lines, words...) by mapping each distinct token to a rune
+// the Text of the returned diffs holds one rune per token
+func diffTokens(tokens1, tokens2 []string) []diffmatchpatch.Diff {
+	ids := make(map[string]rune)
+	toRunes := func(tokens []string) []rune {
+		runes := make([]rune, len(tokens))
+		for i, token := range tokens {
+			id, ok := ids[token]
+			if !ok {
+				id = rune(len(ids) + 1)
+				if id >= 0xD800 { // skip the surrogate range, not valid runes
+					id += 0x800
+				}
+				ids[token] = id
+			}
+			runes[i] = id
+		}
+		return runes
+	}
+
+	dmp := diffmatchpatch.New()
+	return dmp.DiffMainRunes(toRunes(tokens1), toRunes(tokens2), false)
+}
+
+// diffLines returns the line-mode diff of two texts, each diff text holding whole lines
+func diffLines(text1, text2 string) []diffmatchpatch.Diff {
+	dmp := diffmatchpatch.New()
+	runes1, runes2, lineArray := dmp.DiffLinesToRunes(text1, text2)
+	return dmp.DiffCharsToLines(dmp.DiffMainRunes(runes1, runes2, false), lineArray)
+}
+
 // GetContentDiff calculates diffs between two text contents
+// counts are in runes, notes bigger than diffLineModeThreshold are diffed line by line
 func (a *App) GetContentDiff(originalContent, currentContent string) Diff {
-	diffs := ""
-	edit := 0
-	add := 0
-	remove := 0
-
 	dmp := diffmatchpatch.New()
-	diffsObject := dmp.DiffMain(originalContent, currentContent, true)
-	diffs = dmp.DiffToDelta(diffsObject) // tab separated operations
-
-	if diffs == "" {
-		return Diff{diffs, diffsObject, edit, add, remove}
-	}
-
-	// calculate the diff
-	// todo we need to improve this
-	// 	because an edit/delete could be remove 2 carac and add one
-	// 	eg: abcd => xcd, we edit the a, removed the c but the fc say we delete 2 carac and add 1
-	for i, diff := range diffsObject {
-		if diff.Type == diffmatchpatch.DiffDelete { // -
-			if i+1 < len(diffsObject) && diffsObject[i+1].Type == diffmatchpatch.DiffInsert { // +
-				add -= len(diff.Text) // we remove to the add count to avoir duplicate count
-				edit += len(diff.Text)
-			} else {
-				remove += len(diff.Text)
+
+	lineMode := utf8.RuneCountInString(originalContent) > diffLineModeThreshold ||
+		utf8.RuneCountInString(currentContent) > diffLineModeThreshold
+
+	var diffsObject []diffmatchpatch.Diff
+	if lineMode {
+		diffsObject = diffLines(originalContent, currentContent)
+	} else {
+		diffsObject = dmp.DiffMain(originalContent, currentContent, true)
+	}
+
+	result := Diff{
+		DiffString:  dmp.DiffToDelta(diffsObject), // tab separated operations
+		DiffsObject: diffsObject,
+		LineMode:    lineMode,
+	}
+
+	if originalContent == currentContent {
+		return result
+	}
+
+	result.Edit, result.Add, result.Remove = countRunChanges(diffsObject, utf8.RuneCountInString)
+
+	lineDiffs := diffTokens(splitLines(originalContent), splitLines(currentContent))
+	result.LinesChanged, result.LinesAdded, result.LinesRemoved = countRunChanges(lineDiffs, utf8.RuneCountInString)
+
+	wordDiffs := diffTokens(strings.Fields(originalContent), strings.Fields(currentContent))
+	for _, diff := range wordDiffs {
+		switch diff.Type {
+		case diffmatchpatch.DiffInsert:
+			result.WordsAdded += utf8.RuneCountInString(diff.Text)
+		case diffmatchpatch.DiffDelete:
+			result.WordsRemoved += utf8.RuneCountInString(diff.Text)
+		}
+	}
+
+	return result
+}
+
+/**
+ * --- Vault confinement
+ */
+// resolveVaultPath checks that path resolves inside a.rootPath once symlinks are evaluated
+// and returns the cleaned absolute path, otherwise a *VaultPathError
+// note: the path itself may not exist yet (create, rename target), in that case the
+// deepest existing parent is resolved and the missing part is appended to it
+func (a *App) resolveVaultPath(path string) (string, error) {
+	if a.rootPath == "" || path == "" {
+		return "", &VaultPathError{Path: path, Root: a.rootPath}
+	}
+
+	root, err := evalPathSymlinks(a.rootPath)
+	if err != nil {
+		return "", &VaultPathError{Path: path, Root: a.rootPath}
+	}
+
+	cleaned, err := filepath.Abs(path)
+	if err != nil {
+		return "", &VaultPathError{Path: path, Root: a.rootPath}
+	}
+
+	resolved, err := evalPathSymlinks(cleaned)
+	if err != nil {
+		return "", &VaultPathError{Path: path, Root: a.rootPath}
+	}
+
+	if !isPathInside(root, resolved) {
+		return "", &VaultPathError{Path: path, Root: a.rootPath}
+	}
+
+	return cleaned, nil
+}
+
+// resolveVaultChild is resolveVaultPath but also refuses the vault root itself
+// used by destructive operations (delete, rename) that must never target the whole vault
+func (a *App) resolveVaultChild(path string) (string, error) {
+	resolved, err := a.resolveVaultPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	root, _ := filepath.Abs(a.rootPath)
+	if resolved == root {
+		return "", &VaultPathError{Path: path, Root: a.rootPath}
+	}
+
+	return resolved, nil
+}
+
+// evalPathSymlinks evaluates symlinks of the deepest existing part of an absolute path
+// and appends the non-existing remainder untouched
+func evalPathSymlinks(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	var missing []string
+	current := path
+	for {
+		resolved, err := filepath.EvalSymlinks(current)
+		if err == nil {
+			for i := len(missing) - 1; i >= 0; i-- {
+				resolved = filepath.Join(resolved, missing[i])
 			}
+			return resolved, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
 		}
-		if diff.Type == diffmatchpatch.DiffInsert {
-			add += len(diff.Text)
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", err
 		}
+		missing = append(missing, filepath.Base(current))
+		current = parent
 	}
+}
 
-	return Diff{diffs, diffsObject, edit, add, remove}
+// isPathInside reports if target is root or one of its descendants, both must be clean absolute paths
+func isPathInside(root, target string) bool {
+	rel, err := filepath.Rel(root, target)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// vaultsFile returns the file listing the folders the user picked in OpenDirectoryDialog
+// it is kept out of any vault so a script of the webview can't add a folder to it
+func (a *App) vaultsFile() string {
+	if a.vaultsPath != "" {
+		return a.vaultsPath
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "tape", "vaults.json")
+}
+
+// allowedVaults returns the folders picked in OpenDirectoryDialog, symlinks evaluated
+func (a *App) allowedVaults() []string {
+	var vaults []string
+	data, err := os.ReadFile(a.vaultsFile())
+	if err != nil {
+		return vaults
+	}
+	json.Unmarshal(data, &vaults)
+	return vaults
+}
+
+// allowVault records a folder picked in OpenDirectoryDialog, it can then be opened as the vault root
+func (a *App) allowVault(folderPath string) error {
+	resolved, err := evalPathSymlinks(folderPath)
+	if err != nil {
+		return err
+	}
+	vaults := a.allowedVaults()
+	for _, vault := range vaults {
+		if vault == resolved {
+			return nil
+		}
+	}
+
+	data, err := json.MarshalIndent(append(vaults, resolved), "", "  ")
+	if err != nil {
+		return err
+	}
+	file := a.vaultsFile()
+	if file == "" {
+		return fmt.Errorf("no_config_dir")
+	}
+	err = os.MkdirAll(filepath.Dir(file), 0700)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0600)
+}
+
+// isAllowedVault reports if a folder was picked in OpenDirectoryDialog
+func (a *App) isAllowedVault(folderPath string) bool {
+	resolved, err := evalPathSymlinks(folderPath)
+	if err != nil {
+		return false
+	}
+	for _, vault := range a.allowedVaults() {
+		if vault == resolved {
+			return true
+		}
+	}
+	return false
 }
 
 /**
  * --- File system
  */
 // OpenDirectoryDialog opens a directory selection dialog
+// the picked folder is recorded so SaveLastOpenedFolder accepts it as the vault root
 func (a *App) OpenDirectoryDialog() (string, error) {
-	return runtime.OpenDirectoryDialog(a.ctx, runtime.OpenDialogOptions{
+	folderPath, err := runtime.OpenDirectoryDialog(a.ctx, runtime.OpenDialogOptions{
 		Title: "Select directory for markdown notes",
 	})
+	if err != nil || folderPath == "" {
+		return folderPath, err
+	}
+	return folderPath, a.allowVault(folderPath)
 }
 
 // GetDirectoryTree returns the file tree structure for a given directory
 func (a *App) GetDirectoryTree(dirPath string) (*FileItem, error) {
+	dirPath, err := a.resolveVaultPath(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
 	info, err := os.Stat(dirPath)
 	if err != nil {
 		return nil, err
@@ -542,11 +896,43 @@ func (a *App) GetDirectoryTree(dirPath string) (*FileItem, error) {
 		if err != nil {
 			return nil, err
 		}
+		a.addNoteMeta(root)
 	}
 
 	return root, nil
 }
 
+// addNoteMeta adds the front matter properties known by the search index to the notes of a tree
+// the tree doesn't wait for the index: it is loaded in the background and "index:ready" tells when
+func (a *App) addNoteMeta(root *FileItem) {
+	vaultRoot, _ := filepath.Abs(a.rootPath)
+	index := a.loadedSearchIndex()
+	if index == nil || index.root != vaultRoot {
+		a.preloadSearchIndex()
+		return
+	}
+	metas := make(map[string]*NoteMeta)
+	for _, doc := range index.allDocs() {
+		if doc.Meta != nil {
+			metas[doc.Path] = doc.Meta
+		}
+	}
+
+	var walk func(item *FileItem)
+	walk = func(item *FileItem) {
+		if rel, err := a.vaultRelPath(item.Path); err == nil && !item.IsDir {
+			item.Meta = metas[rel]
+		}
+		for _, child := range item.Children {
+			walk(child)
+		}
+		for _, conflict := range item.Conflicts {
+			walk(conflict)
+		}
+	}
+	walk(root)
+}
+
 // buildFileTree recursively builds the file tree
 func (a *App) buildFileTree(parent *FileItem, rootPath string) error {
 	entries, err := os.ReadDir(parent.Path)
@@ -555,6 +941,7 @@ func (a *App) buildFileTree(parent *FileItem, rootPath string) error {
 	}
 
 	var children []*FileItem
+	conflictOf := make(map[*FileItem]string) // conflict copy => path of its original
 
 	for _, entry := range entries {
 		// skip hidden files
@@ -567,8 +954,16 @@ func (a *App) buildFileTree(parent *FileItem, rootPath string) error {
 		}
 
 		realName := entry.Name()
+		original, marker, tool, isConflict := "", "", "", false
+		if !entry.IsDir() {
+			original, marker, tool, isConflict = splitConflictCopy(entry.Name())
+		}
+
 		if a.HasSecurity(rootPath) {
 			name := entry.Name()
+			if isConflict {
+				name = original // the marker added by the sync tool is not part of the encrypted name
+			}
 			if !entry.IsDir() {
 				name = stripFileExt(name)
 			}
@@ -576,7 +971,7 @@ func (a *App) buildFileTree(parent *FileItem, rootPath string) error {
 			if err != nil {
 				realName = entry.Name()
 			} else {
-				realName = string(text)
+				realName = string(text) + marker
 			}
 			if !entry.IsDir() {
 				realName += ".mde"
@@ -589,6 +984,10 @@ func (a *App) buildFileTree(parent *FileItem, rootPath string) error {
 			Path:  fullPath,
 			IsDir: entry.IsDir(),
 		}
+		if isConflict {
+			child.ConflictTool = tool
+			conflictOf[child] = filepath.Join(parent.Path, original)
+		}
 
 		if entry.IsDir() {
 			a.buildFileTree(child, rootPath)
@@ -598,6 +997,8 @@ func (a *App) buildFileTree(parent *FileItem, rootPath string) error {
 		}
 	}
 
+	children = groupConflictCopies(children, conflictOf)
+
 	// Sort children: directories first, then files, all alphabetically
 	sort.Slice(children, func(i, j int) bool {
 		// If one is dir and other is file, dir comes first
@@ -614,6 +1015,11 @@ func (a *App) buildFileTree(parent *FileItem, rootPath string) error {
 
 // ReadFile reads the content of a file
 func (a *App) ReadFile(filePath string) (string, error) {
+	filePath, err := a.resolveVaultPath(filePath)
+	if err != nil {
+		return "", err
+	}
+
 	rawContent, err := os.ReadFile(filePath)
 	if err != nil {
 		return "", err
@@ -636,16 +1042,34 @@ func (a *App) ReadFile(filePath string) (string, error) {
 
 // WriteContentInFile writes content to a file
 func (a *App) WriteContentInFile(filePath, content string) error {
-	if a.HasSecurity(a.rootPath) && isMDE(filePath) {
-		nonce, cipher, err := a.encryptData(a.masterkey, []byte(content))
+	filePath, err := a.resolveVaultPath(filePath)
+	if err != nil {
+		return err
+	}
+
+	// a failing snapshot must never prevent the note from being saved
+	a.snapshotNote(filePath, content)
+
+	hasSecurity, err := a.securityOf(a.rootPath)
+	if err != nil {
+		return err // never write a note in plain text when privacy mode may be on
+	}
+	data := []byte(content)
+	if hasSecurity && isMDE(filePath) {
+		data, err = a.encryptMDE1(data)
 		if err != nil {
 			return err
 		}
-		data := append([]byte(a.cryptVersionMDE1), nonce...)
-		data = append(data, cipher...)
-		return os.WriteFile(filePath, data, 0600)
 	}
-	return os.WriteFile(filePath, []byte(content), 0600)
+
+	err = os.WriteFile(filePath, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	a.scheduleGitCommit(filePath)
+	a.updateSearchIndex(filePath)
+	return nil
 }
 
 // stripFileExt strips .md or .mde extension from a path
@@ -664,7 +1088,11 @@ func stripFileExt(filePath string) string {
 func (a *App) CreateFile(filePath string, filename string) (string, error) {
 	ext := ".md"
 	filename = stripFileExt(filename)
-	if a.HasSecurity(a.rootPath) {
+	hasSecurity, err := a.securityOf(a.rootPath)
+	if err != nil {
+		return "", err
+	}
+	if hasSecurity {
 		ext = ".mde"
 		nonce, ciphertext, err := a.encryptData(a.masterkey, []byte(filename))
 		if err != nil {
@@ -674,7 +1102,10 @@ func (a *App) CreateFile(filePath string, filename string) (string, error) {
 		filename = a.cryptVersionMDE1 + string(base64Payload)
 	}
 
-	filePath = filepath.Join(filePath, filename+ext)
+	filePath, err = a.resolveVaultPath(filepath.Join(filePath, filename+ext))
+	if err != nil {
+		return "", err
+	}
 	isFileExist := a.IsFileExists(filePath)
 	if isFileExist {
 		return "", fmt.Errorf("file_already_exist")
@@ -686,12 +1117,18 @@ func (a *App) CreateFile(filePath string, filename string) (string, error) {
 	}
 	defer file.Close()
 
+	a.scheduleGitCommit(filePath)
+	a.updateSearchIndex(filePath)
 	return filePath, nil
 }
 
 // CreateDirectory creates a new directory
 func (a *App) CreateDirectory(dirPath, foldername string) (string, error) {
-	if a.HasSecurity(a.rootPath) {
+	hasSecurity, err := a.securityOf(a.rootPath)
+	if err != nil {
+		return "", err
+	}
+	if hasSecurity {
 		nonce, cipher, err := a.encryptData(a.masterkey, []byte(foldername))
 		if err != nil {
 			return "", err
@@ -699,30 +1136,69 @@ func (a *App) CreateDirectory(dirPath, foldername string) (string, error) {
 		base64Payload := base64.RawURLEncoding.EncodeToString(append(nonce, cipher...))
 		foldername = a.cryptVersionMDE1 + string(base64Payload)
 	}
-	dirPath = filepath.Join(dirPath, foldername)
+	dirPath, err = a.resolveVaultPath(filepath.Join(dirPath, foldername))
+	if err != nil {
+		return "", err
+	}
 	isFolderExist := a.IsFileExists(dirPath)
 	if isFolderExist {
 		return "", fmt.Errorf("folder_already_exist")
 	}
-	err := os.MkdirAll(dirPath, 0700)
+	err = os.MkdirAll(dirPath, 0700)
 	if err != nil {
 		return "", err
 	}
+	a.updateSearchIndex(dirPath)
 	return dirPath, nil
 }
 
 // DeleteFile deletes a file
 func (a *App) DeleteFile(filePath string) error {
-	return os.Remove(filePath)
+	filePath, err := a.resolveVaultChild(filePath)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filePath)
+	if err != nil {
+		return err
+	}
+	a.scheduleGitCommit(filePath)
+	a.updateSearchIndex(filePath)
+	return a.deleteNoteHistory(filePath)
 }
 
 // DeleteDirectory deletes a directory and all its contents
 func (a *App) DeleteDirectory(dirPath string) error {
-	return os.RemoveAll(dirPath)
+	dirPath, err := a.resolveVaultChild(dirPath)
+	if err != nil {
+		return err
+	}
+	err = os.RemoveAll(dirPath)
+	if err != nil {
+		return err
+	}
+	a.scheduleGitCommit(dirPath)
+	a.updateSearchIndex(dirPath)
+	return a.deleteNoteHistory(dirPath)
 }
 
 // RenameFile renames a file or a directory and returns the actual new path
 func (a *App) RenameFile(oldPath, newPath, filename string, isFile bool) (string, error) {
+	oldPath, err := a.resolveVaultChild(oldPath)
+	if err != nil {
+		return "", err
+	}
+	newPath, err = a.renamedPath(oldPath, newPath, filename, isFile)
+	if err != nil {
+		return "", err
+	}
+
+	return newPath, a.movePath(oldPath, newPath)
+}
+
+// renamedPath returns the path a note or a folder gets when renamed to filename in the folder newPath
+// the name is encrypted in privacy mode, nothing is changed on disk
+func (a *App) renamedPath(oldPath, newPath, filename string, isFile bool) (string, error) {
 	// isFile  true
 	// oldPath  /home/a2n/Documents/empty/MDE1bSxp6UK3J8AO21VUhKHs2BNO5GZcuoakadUByf1GMIM/MDE1uixiuXyP_PRM2QPV5vNNMZMRrrRToFz9GY9rZ1DrHZTGPTvpLw/MDE1_YnB__pmz23UFVquKvbyHV1TGbstz5vMeNxZDQIuT3g5ntGjdjj_/MDE1FNUZ7wJfe9duGe7GWUTtuzMtyrFjph5j_yzNx05UsFRq9iOGqA.mde
 	// newPath  /home/a2n/Documents/empty/MDE1bSxp6UK3J8AO21VUhKHs2BNO5GZcuoakadUByf1GMIM/MDE1uixiuXyP_PRM2QPV5vNNMZMRrrRToFz9GY9rZ1DrHZTGPTvpLw/MDE1_YnB__pmz23UFVquKvbyHV1TGbstz5vMeNxZDQIuT3g5ntGjdjj_
@@ -734,7 +1210,11 @@ func (a *App) RenameFile(oldPath, newPath, filename string, isFile bool) (string
 		filename = stripFileExt(filename)
 	}
 
-	if a.HasSecurity(a.rootPath) && isMDE(oldPath) {
+	hasSecurity, err := a.securityOf(a.rootPath)
+	if err != nil {
+		return "", err
+	}
+	if hasSecurity && isMDE(oldPath) {
 		ext = ".mde"
 		nonce, ciphertext, err := a.encryptData(a.masterkey, []byte(filename))
 		if err != nil {
@@ -748,13 +1228,24 @@ func (a *App) RenameFile(oldPath, newPath, filename string, isFile bool) (string
 		filename = filename + ext
 	}
 
-	newPath = filepath.Join(newPath, filename)
+	return a.resolveVaultChild(filepath.Join(newPath, filename))
+}
+
+// movePath moves a note or a folder to a new path in the vault, along with its history
+func (a *App) movePath(oldPath, newPath string) error {
 	isFileExist := a.IsFileExists(newPath)
 	if isFileExist {
-		return "", fmt.Errorf("file_already_exist")
+		return fmt.Errorf("file_already_exist")
+	}
+
+	err := os.Rename(oldPath, newPath)
+	if err != nil {
+		return err
 	}
+	a.scheduleGitCommit(oldPath, newPath)
+	a.updateSearchIndex(oldPath, newPath)
 
-	return newPath, os.Rename(oldPath, newPath)
+	return a.moveNoteHistory(oldPath, newPath)
 }
 
 // IsFileExists checks if a file exists
@@ -807,11 +1298,16 @@ func (a *App) GetDecryptedFileName(path string) string {
 		ext = ".mde"
 	}
 	filename := stripFileExt(base)
+	marker := ""
+	if original, conflictMarker, _, ok := splitConflictCopy(base); ok {
+		filename = stripFileExt(original)
+		marker = conflictMarker
+	}
 	name, err := a.decryptMDE1([]byte(filename), true)
 	if err != nil {
-		return filename
+		return stripFileExt(base)
 	}
-	return string(name) + ext
+	return string(name) + marker + ext
 }
 
 // GetTapeVersion returns the TAPE_VERSION environment variable
@@ -828,8 +1324,12 @@ func (a *App) getConfigPath(folderPath string) string {
 	return filepath.Join(folderPath, "tape.json")
 }
 
-// LoadConfig loads the configuration from tape.json in the folder
+// LoadConfig loads the configuration from tape.json in the folder, the folder must be in the vault
 func (a *App) LoadConfig(folderPath string) (*Config, error) {
+	folderPath, err := a.resolveVaultPath(folderPath)
+	if err != nil {
+		return &Config{}, err
+	}
 	configPath := a.getConfigPath(folderPath)
 
 	// If config file doesn't exist, return empty config
@@ -851,8 +1351,12 @@ func (a *App) LoadConfig(folderPath string) (*Config, error) {
 	return &config, nil
 }
 
-// SaveConfig saves the configuration to tape.json in the folder
+// SaveConfig saves the configuration to tape.json in the folder, the folder must be in the vault
 func (a *App) SaveConfig(config *Config, folderPath string) error {
+	folderPath, err := a.resolveVaultPath(folderPath)
+	if err != nil {
+		return err
+	}
 	configPath := a.getConfigPath(folderPath)
 
 	data, err := json.MarshalIndent(config, "", "  ")
@@ -860,7 +1364,18 @@ func (a *App) SaveConfig(config *Config, folderPath string) error {
 		return err
 	}
 
-	return os.WriteFile(configPath, data, 0600)
+	err = os.WriteFile(configPath, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	// crypto options may have changed, dropped once written so HasSecurity can't cache the old ones
+	// every entry goes, the vault may also be cached under the path of a symlink to it
+	a.security.mu.Lock()
+	a.security.byRoot = nil
+	a.security.mu.Unlock()
+
+	return nil
 }
 
 // SaveCryptoData saves check, nonce and mode to config
@@ -878,7 +1393,15 @@ func (a *App) SaveCryptoData(folderPath string, check []byte, nonceCheck []byte)
 
 // SaveLastOpenedFolder saves the last opened folder to config
 // since its the first one called we also store the folderPath in the runtime
+// only a folder picked in OpenDirectoryDialog is accepted as the vault root, otherwise "vault_not_allowed"
 func (a *App) SaveLastOpenedFolder(folderPath string) error {
+	if !a.isAllowedVault(folderPath) {
+		return fmt.Errorf("vault_not_allowed")
+	}
+	folderPath, err := filepath.Abs(folderPath)
+	if err != nil {
+		return err
+	}
 	a.rootPath = folderPath // save to runtime
 
 	config, err := a.LoadConfig(folderPath)
@@ -952,6 +1475,10 @@ func (a *App) SaveExpandedFolders(folderPath string, expandedFolders []string) e
 
 // SaveUseEncrypt saves the useEncrypt state to config
 func (a *App) SavePrivacyMode(folderPath string, privacyMode bool) error {
+	folderPath, err := a.resolveVaultPath(folderPath)
+	if err != nil {
+		return err
+	}
 	config, err := a.LoadConfig(folderPath)
 	if err != nil {
 		config = &Config{}
@@ -971,13 +1498,13 @@ func (a *App) getPrivacyMode(folderPath string) bool {
 }
 
 // getCryptoOptions return all related config for crypto
-// privacyMode, Check, NonceCheck
-func (a *App) getCryptoOptions(folderPath string) (bool, []byte, []byte) {
+// privacyMode, Check, NonceCheck, and the error when the config can't be read
+func (a *App) getCryptoOptions(folderPath string) (bool, []byte, []byte, error) {
 	config, err := a.LoadConfig(folderPath)
 	if err != nil {
-		return false, nil, nil
+		return false, nil, nil, err
 	}
-	return config.PrivacyMode, config.Check, config.NonceCheck
+	return config.PrivacyMode, config.Check, config.NonceCheck, nil
 }
 
 // LoadInitialConfig loads initial configuration - returns empty config if no previous folder
@@ -988,34 +1515,99 @@ func (a *App) LoadInitialConfig() (*Config, error) {
 /**
  * --- Search
  */
-// fuzzyMatch performs a simple fuzzy search
-func fuzzyMatch(pattern, text string) bool {
-	pattern = strings.ToLower(pattern)
-	text = strings.ToLower(text)
+// fuzzyScore scores text as a match of pattern, like editor quick-open pickers
+// the pattern runes must appear in order, case-insensitively. The best alignment is kept:
+// consecutive runes, word starts (after a separator or a lowercase to uppercase change)
+// and path segment starts earn bonuses, gaps between matched runes cost points
+func fuzzyScore(pattern, text string) (int, bool) {
+	const (
+		scoreMatch        = 16
+		bonusSegment      = 12 // after a path separator
+		bonusBoundary     = 10 // start of text or after a separator
+		bonusCamel        = 8
+		bonusConsecutive  = 8
+		penaltyGapStart   = 5
+		penaltyGapExtends = 1
+		none              = math.MinInt / 2
+	)
 
-	if pattern == "" {
-		return true
+	patternRunes := []rune(strings.ToLower(pattern))
+	textRunes := []rune(text)
+	if len(patternRunes) == 0 {
+		return 0, true
+	}
+	if len(patternRunes) > len(textRunes) {
+		return 0, false
+	}
+
+	bonus := make([]int, len(textRunes))
+	lower := make([]rune, len(textRunes))
+	for j, r := range textRunes {
+		lower[j] = unicode.ToLower(r)
+		switch {
+		case j == 0:
+			bonus[j] = bonusBoundary
+		case textRunes[j-1] == '/' || textRunes[j-1] == '\\':
+			bonus[j] = bonusSegment
+		case !unicode.IsLetter(textRunes[j-1]) && !unicode.IsDigit(textRunes[j-1]) && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			bonus[j] = bonusBoundary
+		case unicode.IsLower(textRunes[j-1]) && unicode.IsUpper(r), unicode.IsLetter(textRunes[j-1]) && unicode.IsDigit(r):
+			bonus[j] = bonusCamel
+		}
 	}
 
-	patternIdx := 0
-	for _, char := range text {
-		if patternIdx < len(pattern) && char == rune(pattern[patternIdx]) {
-			patternIdx++
+	// previous[j] is the best score of the pattern so far with its last rune matched at j
+	previous := make([]int, len(textRunes))
+	current := make([]int, len(textRunes))
+	for i, p := range patternRunes {
+		gapBest := none // best previous score ending 2 runes back or more, gap penalties applied
+		for j := range textRunes {
+			if gapBest != none {
+				gapBest -= penaltyGapExtends
+			}
+			if i > 0 && j >= 2 && previous[j-2] != none {
+				gapBest = max(gapBest, previous[j-2]-penaltyGapStart)
+			}
+
+			current[j] = none
+			if lower[j] != p || j < i {
+				continue
+			}
+			if i == 0 {
+				current[j] = scoreMatch + bonus[j]
+				continue
+			}
+
+			best := gapBest
+			if previous[j-1] != none {
+				best = max(best, previous[j-1]+bonusConsecutive)
+			}
+			if best != none {
+				current[j] = best + scoreMatch + bonus[j]
+			}
 		}
+		previous, current = current, previous
 	}
 
-	return patternIdx == len(pattern)
+	score := none
+	for _, s := range previous {
+		score = max(score, s)
+	}
+	if score == none {
+		return 0, false
+	}
+	return score, true
 }
 
 // getContextAroundMatch returns context around a match in content
-func getContextAroundMatch(content, query string, matchIndex int) (string, string) {
+func getContextAroundMatch(content string, matchStart, matchEnd int) (string, string) {
 	const contextLength = 100
 
-	start := max(matchIndex-contextLength, 0)
-	end := min(matchIndex+len(query)+contextLength, len(content))
+	start := max(matchStart-contextLength, 0)
+	end := min(matchEnd+contextLength, len(content))
 
 	context := content[start:end]
-	matchText := content[matchIndex : matchIndex+len(query)]
+	matchText := content[matchStart:matchEnd]
 
 	// Clean up context - remove newlines and extra spaces
 	context = strings.ReplaceAll(context, "\n", " ")
@@ -1026,115 +1618,241 @@ func getContextAroundMatch(content, query string, matchIndex int) (string, strin
 }
 
 // SearchFiles searches for files and folders by name and content
-func (a *App) SearchFiles(rootPath string, query string) ([]SearchResult, error) {
-	if query == "" {
-		return []SearchResult{}, nil
+// with options the query can be a regex, case-sensitive or whole word, names are then matched like the content
+func (a *App) SearchFiles(rootPath string, query string, options SearchOptions) ([]SearchResult, error) {
+	page, err := a.SearchFilesPage(rootPath, query, options)
+	if err != nil {
+		return nil, err
 	}
+	return page.Results, nil
+}
 
-	var results []SearchResult
-	query = strings.ToLower(query)
+// SearchFilesPage returns the page of results selected by options.Offset and options.Limit with the total count
+func (a *App) SearchFilesPage(rootPath string, query string, options SearchOptions) (SearchPage, error) {
+	if strings.TrimSpace(query) == "" {
+		return SearchPage{Results: []SearchResult{}}, nil
+	}
 
-	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // skip files with errors
-		}
+	rootPath, err := a.resolveVaultPath(rootPath)
+	if err != nil {
+		return SearchPage{}, err
+	}
 
-		name := info.Name()
+	matcher, err := newSearchMatcher(query, options)
+	if err != nil {
+		return SearchPage{}, err
+	}
 
-		// skip hidden files and directories
-		if strings.HasPrefix(info.Name(), ".") {
-			if info.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
+	ctx := a.startSearch()
+	results := []SearchResult{}
+	truncated, err := a.scanSearch(ctx, rootPath, matcher, func(result *SearchResult, _, _ int) bool {
+		if result != nil {
+			results = append(results, *result)
 		}
+		return true
+	})
+	if err != nil {
+		return SearchPage{}, err
+	}
 
-		// skip tape.config
-		if info.Name() == "tape.json" {
-			return nil
-		}
+	sortSearchResults(results)
+	page := pageSearchResults(results, options)
+	page.Truncated = truncated
+	return page, nil
+}
 
-		// decrypt if needed
-		if a.HasSecurity(rootPath) {
-			name = a.GetDecryptedFileName(path)
+// sortSearchResults sorts the results shown to the user
+func sortSearchResults(results []SearchResult) {
+	// sort results: filename matches first, then folder matches, then content matches, best scores first
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].MatchType != results[j].MatchType {
+			order := map[string]int{"foldername": 0, "filename": 1, "content": 2}
+			return order[results[i].MatchType] < order[results[j].MatchType]
+		}
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
 		}
+		return results[i].Name < results[j].Name
+	})
+}
+
+// searchIndexFor returns the index when rootPath is the opened vault and it is available
+func (a *App) searchIndexFor(rootPath string) *searchIndex {
+	index := a.getSearchIndex()
+	if indexRoot, _ := filepath.Abs(a.rootPath); index == nil || indexRoot != rootPath {
+		return nil
+	}
+	return index
+}
 
-		// get relative path for display
-		relPath, _ := filepath.Rel(rootPath, path)
-		if relPath == "." {
-			return nil // skip root directory
+// displayRelPath returns a path relative to the vault as shown to the user, decrypted in privacy mode
+func (a *App) displayRelPath(rel string) string {
+	if a.HasSecurity(a.rootPath) {
+		rel = a.GetDecryptedFullPath(rel, 0)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// searchTargets returns the notes and folders whose name is matched and the notes whose content is scanned
+// the index narrows the notes to read, a walk of the vault lists them while it is not available
+func (a *App) searchTargets(rootPath string, matcher *searchMatcher) (*searchIndex, []*indexedDoc, []*indexedDoc) {
+	var docs, candidates []*indexedDoc
+	index := a.searchIndexFor(rootPath)
+	if index != nil {
+		docs = index.allDocs()
+		candidates = docs
+		// queries without words (eg: "#") and regexps can't be looked up in the index
+		if matcher.useIndex() {
+			candidates = index.lookup(matcher.query)
 		}
+	} else {
+		docs = a.listVaultDocs(rootPath)
+		candidates = docs
+	}
 
-		// search in directory names
-		if info.IsDir() && fuzzyMatch(query, name) {
-			results = append(results, SearchResult{
-				Path:      path,
-				Name:      name,
-				IsDir:     true,
-				MatchType: "foldername",
-				MatchText: name,
-			})
+	var notes []*indexedDoc
+	for _, doc := range candidates {
+		if !doc.IsDir {
+			notes = append(notes, doc)
 		}
+	}
+	return index, docs, notes
+}
 
-		// search in file names and content (only .md or .mde files)
-		if !info.IsDir() {
-			// check filename match
-			if fuzzyMatch(query, name) {
-				results = append(results, SearchResult{
-					Path:      path,
-					Name:      name,
-					IsDir:     false,
-					MatchType: "filename",
-					MatchText: name,
-				})
-			}
+// scanSearch matches the names then the content of the search targets, in path order
+// visit is called after each target with its result, nil when it didn't match, and stops the scan by returning false
+// the content of the notes is scanned by a pool of workers, visit is still called in order from the calling goroutine
+// true is returned when the time budget of the matcher was spent before every note was scanned
+// an error is returned when ctx is cancelled, eg: by a newer search, or for a blank query which would match every target
+func (a *App) scanSearch(ctx context.Context, rootPath string, matcher *searchMatcher, visit func(result *SearchResult, scanned, total int) bool) (bool, error) {
+	if strings.TrimSpace(matcher.query) == "" {
+		return false, fmt.Errorf("empty_query")
+	}
 
-			// Check content match for markdown files
-			if isMDorMDE(info.Name()) {
-				content, err := a.ReadFile(path)
-				if err != nil || content == "" {
-					return nil
-				}
+	index, docs, notes := a.searchTargets(rootPath, matcher)
+	total := len(docs) + len(notes)
+	scanned := 0
 
-				contentLower := strings.ToLower(content)
-
-				// look for query in content
-				if strings.Contains(contentLower, query) {
-					matchIndex := strings.Index(contentLower, query)
-					matchText, contextText := getContextAroundMatch(content, query, matchIndex)
-
-					results = append(results, SearchResult{
-						Path:        path,
-						Name:        name,
-						IsDir:       false,
-						MatchType:   "content",
-						MatchText:   matchText,
-						ContextText: contextText,
-					})
-				}
+	for _, doc := range docs {
+		if ctx.Err() != nil {
+			return false, fmt.Errorf("search_cancelled")
+		}
+		scanned++
+		score, ok := matcher.matchName(doc.Name, func() string {
+			return a.displayRelPath(doc.Path)
+		})
+
+		var result *SearchResult
+		if ok {
+			matchType := "filename"
+			if doc.IsDir {
+				matchType = "foldername"
+			}
+			result = &SearchResult{
+				Path:      filepath.Join(rootPath, doc.Path),
+				Name:      doc.Name,
+				IsDir:     doc.IsDir,
+				MatchType: matchType,
+				MatchText: doc.Name,
+				Score:     score,
+				Meta:      doc.Meta,
 			}
 		}
+		if !visit(result, scanned, total) {
+			return false, nil
+		}
+	}
 
-		return nil
-	})
-
-	if err != nil {
-		return nil, err
+	type scannedNote struct {
+		index   int
+		result  *SearchResult
+		skipped bool // the time budget was spent before the note was read
 	}
 
-	// sort results: filename matches first, then folder matches, then content matches
-	sort.Slice(results, func(i, j int) bool {
-		if results[i].MatchType != results[j].MatchType {
-			order := map[string]int{"foldername": 0, "filename": 1, "content": 2}
-			return order[results[i].MatchType] < order[results[j].MatchType]
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	done := make(chan scannedNote, len(notes)) // workers never wait for the results to be visited
+	for w := 0; w < min(searchWorkers, len(notes)); w++ {
+		go func() {
+			for i := range jobs {
+				if matcher.expired() {
+					done <- scannedNote{index: i, skipped: true}
+					continue
+				}
+				var result *SearchResult
+				if found, ok := a.searchContent(index, filepath.Join(rootPath, notes[i].Path), notes[i].Name, matcher); ok {
+					result = &found
+				}
+				done <- scannedNote{index: i, result: result}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := range notes {
+			select {
+			case jobs <- i:
+			case <-scanCtx.Done():
+				return
+			}
+		}
+	}()
+
+	// notes are visited in order, the ones scanned ahead wait for the ones before them
+	pending := make(map[int]scannedNote)
+	for next := 0; next < len(notes); {
+		if ctx.Err() != nil {
+			return false, fmt.Errorf("search_cancelled")
+		}
+		select {
+		case note := <-done:
+			pending[note.index] = note
+		case <-ctx.Done():
+			return false, fmt.Errorf("search_cancelled")
 		}
-		return results[i].Name < results[j].Name
-	})
 
-	// todo: atm Limit results to prevent UI overload
-	if len(results) > 60 {
-		results = results[:60]
+		for note, ok := pending[next]; ok; note, ok = pending[next] {
+			delete(pending, next)
+			if note.skipped {
+				return true, nil
+			}
+			scanned++
+			if !visit(note.result, scanned, total) {
+				return false, nil
+			}
+			next++
+		}
 	}
 
-	return results, nil
+	return false, nil
+}
+
+// searchContent looks for the query in the content of a note, read from the index when it keeps it
+func (a *App) searchContent(index *searchIndex, path string, name string, matcher *searchMatcher) (SearchResult, bool) {
+	content, err := a.readIndexedNote(index, path)
+	if err != nil || content == "" {
+		return SearchResult{}, false
+	}
+
+	// look for query in content
+	matches := matcher.findMatches(content)
+	if len(matches) == 0 {
+		return SearchResult{}, false
+	}
+	first := matches[0]
+	matchText, contextText := getContextAroundMatch(content, first.Offset, first.Offset+first.Length)
+
+	return SearchResult{
+		Path:        path,
+		Name:        name,
+		IsDir:       false,
+		MatchType:   "content",
+		MatchText:   matchText,
+		ContextText: contextText,
+		Matches:     matches,
+		Score:       len(matches),
+		Meta:        noteMeta(content),
+	}, true
 }
@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -222,3 +225,235 @@ func TestBuildEncryptedPathsMultipleSiblings(t *testing.T) {
 		t.Fatalf("unexpected encPath for b: %q", result[2].encPath)
 	}
 }
+
+// --- resolveVaultPath ---
+
+// newTestVault returns an App whose rootPath is a fresh temp directory
+func newTestVault(t *testing.T) *App {
+	t.Helper()
+	a := &App{}
+	a.startup(context.Background())
+	a.rootPath = t.TempDir()
+	a.vaultsPath = filepath.Join(t.TempDir(), "vaults.json")
+	a.emit = func(name string, data interface{}) {}
+	t.Cleanup(a.closeSearchIndex)
+	return a
+}
+
+func TestResolveVaultPathInside(t *testing.T) {
+	a := newTestVault(t)
+
+	paths := []string{
+		a.rootPath,
+		filepath.Join(a.rootPath, "note.md"),
+		filepath.Join(a.rootPath, "missing", "deeper", "note.md"),
+		filepath.Join(a.rootPath, "sub", "..", "note.md"),
+	}
+
+	for _, p := range paths {
+		if _, err := a.resolveVaultPath(p); err != nil {
+			t.Fatalf("expected %q to be accepted, got %v", p, err)
+		}
+	}
+}
+
+func TestResolveVaultPathDotDotEscape(t *testing.T) {
+	a := newTestVault(t)
+
+	paths := []string{
+		filepath.Join(a.rootPath, ".."),
+		filepath.Join(a.rootPath, "..", "other", "note.md"),
+		a.rootPath + "/sub/../../note.md",
+		a.rootPath + "-sibling/note.md",
+		"/etc/passwd",
+	}
+
+	for _, p := range paths {
+		_, err := a.resolveVaultPath(p)
+		var pathErr *VaultPathError
+		if !errors.As(err, &pathErr) {
+			t.Fatalf("expected VaultPathError for %q, got %v", p, err)
+		}
+	}
+}
+
+func TestResolveVaultPathSymlinkEscape(t *testing.T) {
+	a := newTestVault(t)
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.md"), []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	link := filepath.Join(a.rootPath, "link")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	if _, err := a.ReadFile(filepath.Join(link, "secret.md")); err == nil {
+		t.Fatal("reading through a symlink leaving the vault must fail")
+	}
+	if err := a.WriteContentInFile(filepath.Join(link, "new.md"), "x"); err == nil {
+		t.Fatal("writing through a symlink leaving the vault must fail")
+	}
+	if a.IsFileExists(filepath.Join(outside, "new.md")) {
+		t.Fatal("file must not be created outside of the vault")
+	}
+}
+
+func TestResolveVaultPathSymlinkedRoot(t *testing.T) {
+	target := t.TempDir()
+	link := filepath.Join(t.TempDir(), "vault")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	a := &App{rootPath: link}
+	resolved, err := a.resolveVaultPath(filepath.Join(link, "note.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved != filepath.Join(link, "note.md") {
+		t.Fatalf("expected the unresolved path to be returned, got %q", resolved)
+	}
+}
+
+func TestDeleteDirectoryRejectsRoot(t *testing.T) {
+	a := newTestVault(t)
+
+	if err := a.DeleteDirectory(a.rootPath); err == nil {
+		t.Fatal("deleting the vault root must fail")
+	}
+	if err := a.DeleteDirectory(filepath.Join(a.rootPath, "..")); err == nil {
+		t.Fatal("deleting the vault parent must fail")
+	}
+	if !a.IsFileExists(a.rootPath) {
+		t.Fatal("vault root must still exist")
+	}
+}
+
+func TestResolveVaultPathNoVault(t *testing.T) {
+	a := &App{}
+	if _, err := a.resolveVaultPath("/tmp/note.md"); err == nil {
+		t.Fatal("paths must be rejected while no vault is opened")
+	}
+}
+
+func TestSaveLastOpenedFolderAllowedVaults(t *testing.T) {
+	a := newTestVault(t)
+	vault := a.rootPath
+	other := t.TempDir()
+
+	if err := a.SaveLastOpenedFolder("/"); err == nil || err.Error() != "vault_not_allowed" {
+		t.Fatalf("expected vault_not_allowed, got %v", err)
+	}
+	if err := a.SaveLastOpenedFolder(other); err == nil {
+		t.Fatal("a folder not picked in the dialog must not become the vault root")
+	}
+	if a.rootPath != vault {
+		t.Fatalf("the vault root must not change, got %q", a.rootPath)
+	}
+
+	if err := a.allowVault(other); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.allowVault(other); err != nil || len(a.allowedVaults()) != 1 {
+		t.Fatalf("a folder must be recorded once, got %v", a.allowedVaults())
+	}
+	if err := a.SaveLastOpenedFolder(filepath.Join(other, ".")); err != nil {
+		t.Fatal(err)
+	}
+	if a.rootPath != other {
+		t.Fatalf("expected the vault root to be %q, got %q", other, a.rootPath)
+	}
+	if config, err := a.LoadConfig(other); err != nil || config.LastOpenedFolder != other {
+		t.Fatalf("unexpected config %+v, %v", config, err)
+	}
+}
+
+func TestConfigConfinedToVault(t *testing.T) {
+	a := newTestVault(t)
+	outside := t.TempDir()
+
+	if err := a.SaveConfig(&Config{}, outside); err == nil {
+		t.Fatal("saving a config outside of the vault must fail")
+	}
+	if a.IsFileExists(filepath.Join(outside, "tape.json")) {
+		t.Fatal("tape.json must not be written outside of the vault")
+	}
+	if err := a.SavePrivacyMode(outside, true); err == nil {
+		t.Fatal("saving the privacy mode outside of the vault must fail")
+	}
+	if _, err := a.LoadConfig(outside); err == nil {
+		t.Fatal("loading a config outside of the vault must fail")
+	}
+	if err := a.SavePrivacyMode(a.rootPath, true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// --- GetContentDiff ---
+
+func TestGetContentDiffReplacementCounts(t *testing.T) {
+	a := newTestApp("testpassword")
+
+	diff := a.GetContentDiff("abcd", "xcd")
+
+	if diff.Edit != 1 || diff.Add != 0 || diff.Remove != 1 {
+		t.Fatalf("expected 1 edit and 1 removal, got edit=%d add=%d remove=%d", diff.Edit, diff.Add, diff.Remove)
+	}
+}
+
+func TestGetContentDiffCountsRunes(t *testing.T) {
+	a := newTestApp("testpassword")
+
+	diff := a.GetContentDiff("café", "café ☕ déjà")
+
+	if diff.Add != 7 || diff.Edit != 0 || diff.Remove != 0 {
+		t.Fatalf("expected 7 added runes, got edit=%d add=%d remove=%d", diff.Edit, diff.Add, diff.Remove)
+	}
+}
+
+func TestGetContentDiffLineAndWordStats(t *testing.T) {
+	a := newTestApp("testpassword")
+	original := "# title\nfirst line\nsecond line\nthird line\n"
+	current := "# title\nfirst line edited\nthird line\nfourth line\nfifth line\n"
+
+	diff := a.GetContentDiff(original, current)
+
+	if diff.LinesChanged != 1 || diff.LinesAdded != 2 || diff.LinesRemoved != 1 {
+		t.Fatalf("unexpected line stats changed=%d added=%d removed=%d", diff.LinesChanged, diff.LinesAdded, diff.LinesRemoved)
+	}
+	if diff.WordsAdded != 5 || diff.WordsRemoved != 2 {
+		t.Fatalf("unexpected word stats added=%d removed=%d", diff.WordsAdded, diff.WordsRemoved)
+	}
+}
+
+func TestGetContentDiffLineModeForLargeNotes(t *testing.T) {
+	a := newTestApp("testpassword")
+	original := strings.Repeat("some line of text\n", diffLineModeThreshold/10)
+	current := original + "one more line\n"
+
+	diff := a.GetContentDiff(original, current)
+
+	if !diff.LineMode {
+		t.Fatal("expected line mode for a large note")
+	}
+	for _, d := range diff.DiffsObject {
+		if !strings.HasSuffix(d.Text, "\n") {
+			t.Fatalf("line mode diffs must hold whole lines, got %q", d.Text)
+		}
+	}
+	if diff.LinesAdded != 1 || diff.Add != len("one more line\n") {
+		t.Fatalf("unexpected stats added lines=%d runes=%d", diff.LinesAdded, diff.Add)
+	}
+}
+
+func TestGetContentDiffIdentical(t *testing.T) {
+	a := newTestApp("testpassword")
+
+	diff := a.GetContentDiff("same", "same")
+
+	if diff.Edit != 0 || diff.Add != 0 || diff.Remove != 0 || diff.LinesChanged != 0 || diff.LineMode {
+		t.Fatalf("expected no change, got %+v", diff)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+type CompareSpan struct {
+	Start int `json:"start"` // rune offsets in the line text
+	End   int `json:"end"`
+}
+
+type CompareLine struct {
+	Number int           `json:"number"` // 1-based, 0 when this side has no line in front of the other one
+	Text   string        `json:"text"`   // without line ending
+	Spans  []CompareSpan `json:"spans"`  // changed parts of a "changed" line
+}
+
+type ComparePair struct {
+	Kind  string      `json:"kind"` // "equal", "changed", "added", "removed"
+	Left  CompareLine `json:"left"`
+	Right CompareLine `json:"right"`
+}
+
+type CompareResult struct {
+	LeftName  string        `json:"leftName"`
+	RightName string        `json:"rightName"`
+	Pairs     []ComparePair `json:"pairs"`
+	Diff      Diff          `json:"diff"`
+}
+
+/**
+ * --- Compare
+ */
+// trimLineEnding removes the line ending of a line
+func trimLineEnding(line string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+}
+
+// intraLineSpans returns the changed rune ranges of both lines
+func intraLineSpans(left, right string) ([]CompareSpan, []CompareSpan) {
+	dmp := diffmatchpatch.New()
+	diffs := dmp.DiffCleanupSemantic(dmp.DiffMain(left, right, false))
+
+	var leftSpans, rightSpans []CompareSpan
+	addSpan := func(spans []CompareSpan, start, end int) []CompareSpan {
+		if len(spans) > 0 && spans[len(spans)-1].End == start {
+			spans[len(spans)-1].End = end
+			return spans
+		}
+		return append(spans, CompareSpan{start, end})
+	}
+
+	leftPos, rightPos := 0, 0
+	for _, diff := range diffs {
+		size := utf8.RuneCountInString(diff.Text)
+		switch diff.Type {
+		case diffmatchpatch.DiffEqual:
+			leftPos += size
+			rightPos += size
+		case diffmatchpatch.DiffDelete:
+			leftSpans = addSpan(leftSpans, leftPos, leftPos+size)
+			leftPos += size
+		case diffmatchpatch.DiffInsert:
+			rightSpans = addSpan(rightSpans, rightPos, rightPos+size)
+			rightPos += size
+		}
+	}
+
+	return leftSpans, rightSpans
+}
+
+// sideBySide aligns the lines of two texts: unchanged lines face each other, replaced lines are
+// paired one to one with their intra-line changes and the remaining ones face an empty line
+func sideBySide(left, right string) []ComparePair {
+	pairs := []ComparePair{}
+	leftNumber, rightNumber := 0, 0
+	var removed, added []string
+
+	flush := func() {
+		for i := 0; i < max(len(removed), len(added)); i++ {
+			pair := ComparePair{}
+			if i < len(removed) {
+				leftNumber++
+				pair.Left = CompareLine{Number: leftNumber, Text: trimLineEnding(removed[i])}
+			}
+			if i < len(added) {
+				rightNumber++
+				pair.Right = CompareLine{Number: rightNumber, Text: trimLineEnding(added[i])}
+			}
+
+			switch {
+			case i >= len(added):
+				pair.Kind = "removed"
+			case i >= len(removed):
+				pair.Kind = "added"
+			default:
+				pair.Kind = "changed"
+				pair.Left.Spans, pair.Right.Spans = intraLineSpans(pair.Left.Text, pair.Right.Text)
+			}
+			pairs = append(pairs, pair)
+		}
+		removed, added = nil, nil
+	}
+
+	for _, diff := range diffLines(left, right) {
+		lines := splitLines(diff.Text)
+		switch diff.Type {
+		case diffmatchpatch.DiffDelete:
+			removed = append(removed, lines...)
+		case diffmatchpatch.DiffInsert:
+			added = append(added, lines...)
+		default:
+			flush()
+			for _, line := range lines {
+				leftNumber++
+				rightNumber++
+				text := trimLineEnding(line)
+				pairs = append(pairs, ComparePair{
+					Kind:  "equal",
+					Left:  CompareLine{Number: leftNumber, Text: text},
+					Right: CompareLine{Number: rightNumber, Text: text},
+				})
+			}
+		}
+	}
+	flush()
+
+	return pairs
+}
+
+// displayName returns the name of a note as shown to the user, decrypted in privacy mode
+func (a *App) displayName(path string) string {
+	if a.HasSecurity(a.rootPath) && isMDE(path) {
+		return a.GetDecryptedFileName(path)
+	}
+	return filepath.Base(path)
+}
+
+// CompareFiles returns a side by side comparison of two notes
+func (a *App) CompareFiles(pathA string, pathB string) (CompareResult, error) {
+	contentA, err := a.ReadFile(pathA)
+	if err != nil {
+		return CompareResult{}, err
+	}
+	contentB, err := a.ReadFile(pathB)
+	if err != nil {
+		return CompareResult{}, err
+	}
+
+	return CompareResult{
+		LeftName:  a.displayName(pathA),
+		RightName: a.displayName(pathB),
+		Pairs:     sideBySide(contentA, contentB),
+		Diff:      a.GetContentDiff(contentA, contentB),
+	}, nil
+}
+
+// CompareNoteVersions returns a side by side comparison of two saved versions of a note
+// an empty id stands for the current content of the note
+func (a *App) CompareNoteVersions(path string, idA string, idB string) (CompareResult, error) {
+	read := func(id string) (string, error) {
+		if id == "" {
+			return a.ReadFile(path)
+		}
+		version, err := a.ReadNoteVersion(path, id)
+		return version.Content, err
+	}
+
+	contentA, err := read(idA)
+	if err != nil {
+		return CompareResult{}, err
+	}
+	contentB, err := read(idB)
+	if err != nil {
+		return CompareResult{}, err
+	}
+
+	name := a.displayName(path)
+	return CompareResult{
+		LeftName:  name,
+		RightName: name,
+		Pairs:     sideBySide(contentA, contentB),
+		Diff:      a.GetContentDiff(contentA, contentB),
+	}, nil
+}
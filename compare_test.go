@@ -0,0 +1,83 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSideBySide(t *testing.T) {
+	left := "title\nsame line\nold words here\nremoved line\nend\n"
+	right := "title\nsame line\nnew words here\nend\nadded line\n"
+
+	pairs := sideBySide(left, right)
+
+	kinds := []string{}
+	for _, pair := range pairs {
+		kinds = append(kinds, pair.Kind)
+	}
+	expected := []string{"equal", "equal", "changed", "removed", "equal", "added"}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Fatalf("expected %v, got %v", expected, kinds)
+	}
+
+	changed := pairs[2]
+	if changed.Left.Number != 3 || changed.Right.Number != 3 || changed.Left.Text != "old words here" {
+		t.Fatalf("unexpected changed pair %+v", changed)
+	}
+	if !reflect.DeepEqual(changed.Left.Spans, []CompareSpan{{0, 3}}) || !reflect.DeepEqual(changed.Right.Spans, []CompareSpan{{0, 3}}) {
+		t.Fatalf("unexpected spans %+v / %+v", changed.Left.Spans, changed.Right.Spans)
+	}
+
+	if pairs[3].Right.Number != 0 || pairs[5].Left.Number != 0 {
+		t.Fatal("removed and added lines must face an empty line")
+	}
+	if pairs[4].Left.Number != 5 || pairs[4].Right.Number != 4 {
+		t.Fatalf("unexpected line numbers %+v", pairs[4])
+	}
+}
+
+func TestIntraLineSpansRunes(t *testing.T) {
+	left, right := intraLineSpans("déjà vu", "déjà su")
+
+	if !reflect.DeepEqual(left, []CompareSpan{{5, 6}}) || !reflect.DeepEqual(right, []CompareSpan{{5, 6}}) {
+		t.Fatalf("expected rune based spans, got %+v / %+v", left, right)
+	}
+}
+
+func TestCompareFilesEncrypted(t *testing.T) {
+	a := newTestVault(t)
+	a.SetupPassword("testpassword", a.rootPath)
+	pathA, _ := a.CreateFile(a.rootPath, "draft.md")
+	pathB, _ := a.CreateFile(a.rootPath, "final.md")
+	a.WriteContentInFile(pathA, "one\ntwo\n")
+	a.WriteContentInFile(pathB, "one\n2\n")
+
+	result, err := a.CompareFiles(pathA, pathB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.LeftName != "draft.mde" || result.RightName != "final.mde" {
+		t.Fatalf("expected decrypted names, got %q and %q", result.LeftName, result.RightName)
+	}
+	if len(result.Pairs) != 2 || result.Pairs[1].Kind != "changed" || result.Pairs[1].Left.Text != "two" {
+		t.Fatalf("unexpected pairs %+v", result.Pairs)
+	}
+}
+
+func TestCompareNoteVersions(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+	a.WriteContentInFile(note, "first\n")
+	a.WriteContentInFile(note, "second\n")
+	versions, _ := a.ListNoteVersions(note)
+
+	result, err := a.CompareNoteVersions(note, versions[1].ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Pairs) != 1 || result.Pairs[0].Left.Text != "first" || result.Pairs[0].Right.Text != "second" {
+		t.Fatalf("unexpected pairs %+v", result.Pairs)
+	}
+}
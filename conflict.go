@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// conflictCopyPatterns matches the name (without extension) of conflict copies created by sync tools
+// group 1 is the original name, group 2 the marker added by the tool
+var conflictCopyPatterns = []struct {
+	tool    string
+	pattern *regexp.Regexp
+}{
+	{"syncthing", regexp.MustCompile(`^(.+)(\.sync-conflict-\d{8}-\d{6}(?:-[A-Z0-9]{7})?)$`)},
+	{"dropbox", regexp.MustCompile(`^(.+)( \([^()]+'s conflicted copy(?: [^()]*)?\))$`)},
+	{"nextcloud", regexp.MustCompile(`^(.+)( \(conflicted copy(?: [^()]*)?\))$`)},
+	{"owncloud", regexp.MustCompile(`^(.+)(_conflict-\d{8}-\d{6})$`)},
+	{"seafile", regexp.MustCompile(`^(.+)( \(SFConflict [^()]*\))$`)},
+}
+
+/**
+ * --- Sync conflicts
+ */
+// splitConflictCopy checks if filename is a conflict copy made by a sync tool
+// and returns the original filename, the marker added by the tool and the tool name
+// eg: note.sync-conflict-20261010-101500-ABCDEFG.md => note.md, .sync-conflict-20261010-101500-ABCDEFG, syncthing
+// note: it works on encrypted names too since the marker is added around the MDE1 payload
+func splitConflictCopy(filename string) (original, marker, tool string, ok bool) {
+	stem := stripFileExt(filename)
+	ext := filename[len(stem):]
+
+	for _, candidate := range conflictCopyPatterns {
+		match := candidate.pattern.FindStringSubmatch(stem)
+		if match != nil {
+			return match[1] + ext, match[2], candidate.tool, true
+		}
+	}
+	return "", "", "", false
+}
+
+// groupConflictCopies moves conflict copies into the Conflicts of their original
+// copies whose original doesn't exist anymore are left as regular children
+func groupConflictCopies(children []*FileItem, conflictOf map[*FileItem]string) []*FileItem {
+	if len(conflictOf) == 0 {
+		return children
+	}
+
+	byPath := make(map[string]*FileItem)
+	for _, child := range children {
+		if _, isCopy := conflictOf[child]; !isCopy {
+			byPath[child.Path] = child
+		}
+	}
+
+	var grouped []*FileItem
+	for _, child := range children {
+		originalPath, isCopy := conflictOf[child]
+		if original, found := byPath[originalPath]; isCopy && found {
+			original.Conflicts = append(original.Conflicts, child)
+			continue
+		}
+		grouped = append(grouped, child)
+	}
+	return grouped
+}
+
+// commonLines returns the lines both texts share, in order
+// used as the merge base of two bodies without known ancestor: changes of each side become insertions
+func commonLines(text1, text2 string) string {
+	dmp := diffmatchpatch.New()
+	runes1, runes2, lineArray := dmp.DiffLinesToRunes(text1, text2)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMainRunes(runes1, runes2, false), lineArray)
+
+	var common strings.Builder
+	for _, diff := range diffs {
+		if diff.Type == diffmatchpatch.DiffEqual {
+			common.WriteString(diff.Text)
+		}
+	}
+	return common.String()
+}
+
+// checkConflictCopy checks that copy is a conflict copy of original, so resolving it only deletes such a copy
+func (a *App) checkConflictCopy(original, copy string) error {
+	original, err := a.resolveVaultChild(original)
+	if err != nil {
+		return err
+	}
+	copy, err = a.resolveVaultChild(copy)
+	if err != nil {
+		return err
+	}
+
+	name, _, _, ok := splitConflictCopy(filepath.Base(copy))
+	if !ok || filepath.Dir(copy) != filepath.Dir(original) || name != filepath.Base(original) {
+		return fmt.Errorf("not_a_conflict_copy")
+	}
+	return nil
+}
+
+// ResolveConflict resolves a sync-tool conflict copy against its original note
+// strategies:
+// - "original": keep the original, the copy is deleted
+// - "copy": the original gets the copy content, the copy is deleted
+// - "merge": both bodies are merged, files are only changed if the merge is clean
+// - "union": both bodies are merged, overlapping changes are all kept one after the other
+// copy must be a conflict copy of original in the same folder, otherwise "not_a_conflict_copy"
+func (a *App) ResolveConflict(original, copy, strategy string) (MergeResult, error) {
+	err := a.checkConflictCopy(original, copy)
+	if err != nil {
+		return MergeResult{}, err
+	}
+
+	originalContent, err := a.ReadFile(original)
+	if err != nil {
+		return MergeResult{}, err
+	}
+	copyContent, err := a.ReadFile(copy)
+	if err != nil {
+		return MergeResult{}, err
+	}
+
+	var result MergeResult
+	switch strategy {
+	case "original":
+		result = MergeResult{Merged: originalContent, Clean: true, Conflicts: []MergeConflict{}}
+	case "copy":
+		result = MergeResult{Merged: copyContent, Clean: true, Conflicts: []MergeConflict{}}
+	case "merge":
+		result = a.MergeContents(commonLines(originalContent, copyContent), originalContent, copyContent)
+		if !result.Clean {
+			return result, nil
+		}
+	case "union":
+		result = mergeLines(commonLines(originalContent, copyContent), originalContent, copyContent, true)
+	default:
+		return MergeResult{}, fmt.Errorf("unknown_strategy")
+	}
+
+	if result.Merged != originalContent {
+		err = a.WriteContentInFile(original, result.Merged)
+		if err != nil {
+			return MergeResult{}, err
+		}
+	}
+
+	return result, a.DeleteFile(copy)
+}
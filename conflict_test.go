@@ -0,0 +1,157 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitConflictCopy(t *testing.T) {
+	tests := []struct {
+		filename string
+		original string
+		tool     string
+	}{
+		{"note.sync-conflict-20261010-101500-ABCDEFG.md", "note.md", "syncthing"},
+		{"note (conflicted copy 2026-10-10 101500).md", "note.md", "nextcloud"},
+		{"note (conflicted copy).md", "note.md", "nextcloud"},
+		{"note (Jane's conflicted copy 2026-10-10).md", "note.md", "dropbox"},
+		{"note_conflict-20261010-101500.md", "note.md", "owncloud"},
+		{"note (SFConflict jane@example.com 2026-10-10-10-15-00).md", "note.md", "seafile"},
+		{"MDE1abc-_XYZ.sync-conflict-20261010-101500-ABCDEFG.mde", "MDE1abc-_XYZ.mde", "syncthing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			original, _, tool, ok := splitConflictCopy(tt.filename)
+			if !ok {
+				t.Fatal("expected a conflict copy")
+			}
+			if original != tt.original || tool != tt.tool {
+				t.Fatalf("expected %q from %s, got %q from %s", tt.original, tt.tool, original, tool)
+			}
+		})
+	}
+
+	for _, filename := range []string{"note.md", "conflicted copy.md", "note (1).md"} {
+		if _, _, _, ok := splitConflictCopy(filename); ok {
+			t.Fatalf("%q must not be detected as a conflict copy", filename)
+		}
+	}
+}
+
+func TestBuildFileTreeGroupsConflictCopies(t *testing.T) {
+	a := newTestVault(t)
+	for _, name := range []string{"note.md", "note.sync-conflict-20261010-101500-ABCDEFG.md", "lost (conflicted copy).md"} {
+		os.WriteFile(filepath.Join(a.rootPath, name), []byte("x"), 0600)
+	}
+
+	tree, err := a.GetDirectoryTree(a.rootPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tree.Children) != 2 {
+		t.Fatalf("expected the copy to be grouped with its original, got %d children", len(tree.Children))
+	}
+	note := tree.Children[1]
+	if note.Name != "note.md" || len(note.Conflicts) != 1 || note.Conflicts[0].ConflictTool != "syncthing" {
+		t.Fatalf("unexpected original %+v", note)
+	}
+	if tree.Children[0].ConflictTool != "nextcloud" {
+		t.Fatal("a copy without original must stay visible")
+	}
+}
+
+func TestBuildFileTreeGroupsEncryptedConflictCopies(t *testing.T) {
+	a := newTestVault(t)
+	a.SetupPassword("testpassword", a.rootPath)
+
+	encrypted, _ := a.encryptName("note.md", false)
+	copyName := stripFileExt(encrypted) + ".sync-conflict-20261010-101500-ABCDEFG.mde"
+	os.WriteFile(filepath.Join(a.rootPath, encrypted), nil, 0600)
+	os.WriteFile(filepath.Join(a.rootPath, copyName), nil, 0600)
+
+	tree, err := a.GetDirectoryTree(a.rootPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tree.Children) != 1 || len(tree.Children[0].Conflicts) != 1 {
+		t.Fatalf("expected the encrypted copy to be grouped, got %+v", tree.Children)
+	}
+	if name := tree.Children[0].Conflicts[0].Name; name != "note.sync-conflict-20261010-101500-ABCDEFG.mde" {
+		t.Fatalf("expected a decrypted copy name, got %q", name)
+	}
+	if name := a.GetDecryptedFileName(filepath.Join(a.rootPath, copyName)); name != "note.sync-conflict-20261010-101500-ABCDEFG.mde" {
+		t.Fatalf("unexpected decrypted file name %q", name)
+	}
+}
+
+func TestResolveConflict(t *testing.T) {
+	a := newTestVault(t)
+	original := filepath.Join(a.rootPath, "note.md")
+	copy := filepath.Join(a.rootPath, "note (conflicted copy).md")
+
+	write := func(originalContent, copyContent string) {
+		os.WriteFile(original, []byte(originalContent), 0600)
+		os.WriteFile(copy, []byte(copyContent), 0600)
+	}
+
+	write("a\nb\nc\n", "a\nb\nc\nd\n")
+	result, err := a.ResolveConflict(original, copy, "merge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := a.ReadFile(original)
+	if !result.Clean || content != "a\nb\nc\nd\n" {
+		t.Fatalf("unexpected merged content %q", content)
+	}
+	if a.IsFileExists(copy) {
+		t.Fatal("the copy must be deleted once resolved")
+	}
+
+	write("a\nours\nc\n", "a\ntheirs\nc\n")
+	result, _ = a.ResolveConflict(original, copy, "merge")
+	if result.Clean || !a.IsFileExists(copy) {
+		t.Fatal("a conflicting merge must leave both files untouched")
+	}
+
+	result, _ = a.ResolveConflict(original, copy, "union")
+	content, _ = a.ReadFile(original)
+	if content != "a\nours\ntheirs\nc\n" {
+		t.Fatalf("unexpected union content %q", content)
+	}
+
+	write("original", "copy")
+	a.ResolveConflict(original, copy, "copy")
+	content, _ = a.ReadFile(original)
+	if content != "copy" {
+		t.Fatalf("expected the copy content, got %q", content)
+	}
+
+	write("original", "copy")
+	if _, err := a.ResolveConflict(original, copy, "unknown"); err == nil {
+		t.Fatal("expected an error for an unknown strategy")
+	}
+
+	other := filepath.Join(a.rootPath, "other.md")
+	os.WriteFile(other, []byte("other"), 0600)
+	os.MkdirAll(filepath.Join(a.rootPath, "sub"), 0755)
+	elsewhere := filepath.Join(a.rootPath, "sub", "note (conflicted copy).md")
+	os.WriteFile(elsewhere, []byte("copy"), 0600)
+	pairs := [][2]string{
+		{original, other},     // not a conflict copy
+		{other, copy},         // the copy of another note
+		{original, elsewhere}, // a copy in another folder
+		{copy, original},
+	}
+	for _, pair := range pairs {
+		if _, err := a.ResolveConflict(pair[0], pair[1], "original"); err == nil || err.Error() != "not_a_conflict_copy" {
+			t.Fatalf("%q %q: expected not_a_conflict_copy, got %v", pair[0], pair[1], err)
+		}
+	}
+	if !a.IsFileExists(other) || !a.IsFileExists(elsewhere) || !a.IsFileExists(original) {
+		t.Fatal("a file that isn't a conflict copy of the original must not be deleted")
+	}
+}
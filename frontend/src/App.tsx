@@ -35,7 +35,7 @@ import {
   SaveLastOpenedFile,
   SaveExpandedFolders,
   SaveViewMode,
-  SearchFiles,
+  SearchFilesPage,
   SetupPassword,
   HasSecurity,
   PasswordIsCorrect,
@@ -43,12 +43,14 @@ import {
   WriteContentInFile,
   GetOs,
 } from "../wailsjs/go/main/App";
+import { main } from "../wailsjs/go/models";
+import { EventsOn } from "../wailsjs/runtime/runtime";
 import appIcon from './assets/images/logo.png';
 import appIconBck from './assets/images/logo-background.png';
 import Stats from "./components/Stats";
 import handleKeys from "./services/handleKeys";
 import SettingsPopover from './components/SettingsPopover';
-import type { FileItem, ViewMode, ThemeMode, UIThemeMode, SearchResult } from './types/types';
+import type { FileItem, ViewMode, ThemeMode, UIThemeMode, SearchPage } from './types/types';
 import UseEncVaultModal from './components/UseEncVaultModal';
 import UnlockVaultModal from './components/UnlockVaultModal';
 
@@ -253,6 +255,8 @@ function App() {
       const dPath = rootPath ?? await OpenDirectoryDialog();
       if (dPath) {
         setDirPath(dPath);
+        // file operations are confined to the vault root on Go side, set it before reading the tree
+        await SaveLastOpenedFolder(dPath);
         const tree = await GetDirectoryTree(dPath);
 
         // ask if the user want an encrypted vault or not
@@ -273,6 +277,11 @@ function App() {
       }
     } catch (error) {
       console.error('Error opening directory:', error);
+      if (rootPath) {
+        // a remembered folder not picked in the dialog is refused as the vault root, pick it again
+        localStorage.removeItem('lastOpenedFolder');
+        setDirPath("");
+      }
     }
   };
 
@@ -327,17 +336,17 @@ function App() {
   };
 
   // get the search result from the go backend
-  const handleSearch = async (query: string): Promise<SearchResult[]> => {
+  const handleSearch = async (query: string): Promise<SearchPage | null> => {
     if (!fileTree?.path || !query.trim()) {
-      return [];
+      return null;
     }
 
     try {
-      const results = await SearchFiles(fileTree.path, query);
-      return results as SearchResult[];
+      const page = await SearchFilesPage(fileTree.path, query, main.SearchOptions.createFrom({}));
+      return page as SearchPage;
     } catch (error) {
       console.error('Search error:', error);
-      return [];
+      return null;
     }
   };
 
@@ -398,6 +407,19 @@ function App() {
     }
   };
 
+  // the tree is shown before the search index is loaded, it gets the properties of the notes once it is
+  useEffect(() => {
+    if (!fileTree?.path) return;
+    const path = fileTree.path;
+    return EventsOn("index:ready", async () => {
+      try {
+        setFileTree(await GetDirectoryTree(path));
+      } catch (error) {
+        console.error('Error refreshing file tree:', error);
+      }
+    });
+  }, [fileTree?.path]);
+
   const handleCreateFile = (parentPath?: string) => {
     if (!fileTree && !parentPath) return;
     setCurrentParentPath(parentPath || fileTree!.path);
@@ -10,6 +10,7 @@ import rehypeHighlight from "rehype-highlight"; // code colorization
 import rehypeCallouts from "rehype-callouts"; // to html
 import rehypeStringify from "rehype-stringify"; // render blockquote-based callouts (admonitions/alerts)
 import rehypeHighlightLines from "rehype-highlight-code-lines";
+import rehypeHeadingIds from "../services/rehypeHeadingIds"; // ids the table of contents links to
 
 interface MarkdownReaderProps {
   content: string;
@@ -84,6 +85,7 @@ const MarkdownReader: React.FC<MarkdownReaderProps> = ({ content, filePath, onCo
           remarkPlugins={[remarkGfm, codeTitle]}
           rehypePlugins={[
             remarkRehype,
+            rehypeHeadingIds,
             rehypeHighlight,
             [rehypeCallouts, { theme: 'github' }],
             [rehypeHighlightLines, { showLineNumbers: true }],
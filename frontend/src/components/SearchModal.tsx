@@ -1,7 +1,7 @@
 import React, { useState, useEffect, useRef } from 'react';
 import {Dialog, TextField, Text, Flex, Separator} from '@radix-ui/themes';
 import {FileText, Folder, AlertCircle, SearchIcon} from 'lucide-react';
-import { FileItem } from '../types/types';
+import { FileItem, SearchPage } from '../types/types';
 
 interface SearchResult {
   path: string;
@@ -16,7 +16,7 @@ interface SearchModalProps {
   isOpen: boolean;
   onClose: () => void;
   onFileSelect: (item: FileItem) => void;
-  onSearch: (query: string) => Promise<SearchResult[]>;
+  onSearch: (query: string) => Promise<SearchPage | null>;
 }
 
 type resultLength = {
@@ -28,6 +28,7 @@ type resultLength = {
 const SearchModal: React.FC<SearchModalProps> = ({isOpen, onClose, onFileSelect, onSearch}) => {
   const [query, setQuery] = useState('');
   const [results, setResults] = useState<SearchResult[]>([]);
+  const [truncated, setTruncated] = useState(false);
   const [selectedIndex, setSelectedIndex] = useState(0);
   const [isLoading, setIsLoading] = useState(false);
 
@@ -41,6 +42,7 @@ const SearchModal: React.FC<SearchModalProps> = ({isOpen, onClose, onFileSelect,
       inputRef.current.focus();
       setQuery('');
       setResults([]);
+      setTruncated(false);
       setSelectedIndex(0);
     }
   }, [isOpen]);
@@ -51,6 +53,7 @@ const SearchModal: React.FC<SearchModalProps> = ({isOpen, onClose, onFileSelect,
 
     if (query.trim() === '') {
       setResults([]);
+      setTruncated(false);
       setIsLoading(false);
       setSelectedIndex(0);
       return;
@@ -60,12 +63,14 @@ const SearchModal: React.FC<SearchModalProps> = ({isOpen, onClose, onFileSelect,
 
     debounceRef.current = window.setTimeout(async () => {
       try {
-        const searchResults = await onSearch(query);
-        setResults(searchResults);
+        const page = await onSearch(query);
+        setResults(page?.results ?? []);
+        setTruncated(page?.truncated ?? false);
         setSelectedIndex(0);
       } catch (error) {
         console.error('Search error:', error);
         setResults([]);
+        setTruncated(false);
       } finally {
         setIsLoading(false);
       }
@@ -210,6 +215,7 @@ const SearchModal: React.FC<SearchModalProps> = ({isOpen, onClose, onFileSelect,
           </TextField.Root>
           <div id="search-info" className="vt32">
             Folder: {getResultLength().d} File: {getResultLength().f} String: {getResultLength().s}
+            {truncated && " - The search took too long, some notes were not searched"}
           </div>
           <Separator style={{width: "100%"}}/>
         </Flex>
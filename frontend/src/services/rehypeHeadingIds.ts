@@ -0,0 +1,67 @@
+// rehypeHeadingIds gives the headings of the reader the ids the table of contents links to,
+// made like headingSlug and uniqueSlug do on the Go side (markdown.go), eg: "goals-1" for the second "Goals"
+
+interface HastNode {
+  type: string;
+  tagName?: string;
+  value?: string;
+  properties?: Record<string, unknown>;
+  children?: HastNode[];
+}
+
+const headingTags = ["h1", "h2", "h3", "h4", "h5", "h6"];
+
+// wiki links aren't rendered by the reader, their alias or target is kept like in headingText
+const wikiLinkPattern = /!?\[\[([^\]|]*)(?:\|([^\]]*))?\]\]/g;
+
+// textOf returns the text of a node and its children
+const textOf = (node: HastNode): string => {
+  if (node.type === "text") return node.value ?? "";
+  return (node.children ?? []).map(textOf).join("");
+};
+
+// headingSlug lowercases the text, drops punctuation and replaces spaces by "-"
+export const headingSlug = (text: string): string => {
+  let slug = "";
+  for (const char of text.trim().toLowerCase()) {
+    if (char === " ") {
+      slug += "-";
+    } else if (/^[-_\p{L}\p{Nd}\p{M}]$/u.test(char)) {
+      slug += char;
+    }
+  }
+  return slug;
+};
+
+// uniqueSlug suffixes a slug already used in the note with "-1", "-2"...
+const uniqueSlug = (used: Set<string>, slug: string): string => {
+  let unique = slug;
+  for (let i = 1; used.has(unique); i++) {
+    unique = `${slug}-${i}`;
+  }
+  used.add(unique);
+  return unique;
+};
+
+// headingText returns the text of a heading as the Go side reads it: wiki links replaced by their text,
+// the lines of a setext heading joined by a space
+export const headingText = (text: string): string => {
+  return text
+    .replace(wikiLinkPattern, (_, target: string, alias?: string) => alias || target)
+    .replace(/[ \t]*\r?\n[ \t]*/g, " ")
+    .trim();
+};
+
+const rehypeHeadingIds = () => (tree: HastNode) => {
+  const used = new Set<string>();
+  const visit = (node: HastNode) => {
+    if (node.type === "element" && headingTags.includes(node.tagName ?? "")) {
+      node.properties = { ...node.properties, id: uniqueSlug(used, headingSlug(headingText(textOf(node)))) };
+      return;
+    }
+    node.children?.forEach(visit);
+  };
+  visit(tree);
+};
+
+export default rehypeHeadingIds;
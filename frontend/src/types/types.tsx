@@ -3,6 +3,7 @@ export interface FileItem {
   path: string;
   isDir: boolean;
   children?: FileItem[];
+  meta?: NoteMeta;
 }
 
 export interface SearchResult {
@@ -12,9 +13,66 @@ export interface SearchResult {
   matchType: 'filename' | 'foldername' | 'content';
   matchText: string;
   contextText: string;
+  matches?: SearchMatch[];
+  score: number;
+  meta?: NoteMeta;
+}
+
+export interface SearchPage {
+  results: SearchResult[];
+  offset: number;
+  total: number;
+  hasMore: boolean;
+  truncated: boolean; // the search took too long, some notes were not scanned
+}
+
+export interface NoteMeta {
+  title?: string;
+  tags?: string[];
+  date?: string;
+}
+
+export interface TagInfo {
+  tag: string;
+  name: string;
+  count: number;
+  total: number;
+  children?: TagInfo[];
+}
+
+export interface SearchMatch {
+  line: number;
+  column: number;
+  offset: number;
+  length: number;
+  snippet: string;
+  start: number;
+  end: number;
+  runeStart: number;
+  runeEnd: number;
+  element: 'code' | 'heading' | 'link' | 'text' | 'frontmatter';
 }
 
 export type ViewMode = 'editor' | 'reader';
 export type ThemeMode = 'system' | 'light' | 'dark';
 export type UIThemeMode = 'original' | 'modern' | 'agrume';
 
+
+export interface Task {
+  path: string;
+  name: string;
+  line: number;
+  text: string;
+  done: boolean;
+  due: string;
+  priority: string;
+  tags: string[];
+}
+
+export interface OutlineHeading {
+  level: number;
+  text: string;
+  line: number;
+  anchor: string;
+  children?: OutlineHeading[];
+}
@@ -56,7 +56,9 @@ export function SaveUITheme(arg1:string,arg2:string):Promise<void>;
 
 export function SaveViewMode(arg1:string,arg2:string):Promise<void>;
 
-export function SearchFiles(arg1:string,arg2:string):Promise<Array<main.SearchResult>>;
+export function SearchFiles(arg1:string,arg2:string,arg3:main.SearchOptions):Promise<Array<main.SearchResult>>;
+
+export function SearchFilesPage(arg1:string,arg2:string,arg3:main.SearchOptions):Promise<main.SearchPage>;
 
 export function SetupPassword(arg1:string,arg2:string):Promise<string>;
 
@@ -110,8 +110,12 @@ export function SaveViewMode(arg1, arg2) {
   return window['go']['main']['App']['SaveViewMode'](arg1, arg2);
 }
 
-export function SearchFiles(arg1, arg2) {
-  return window['go']['main']['App']['SearchFiles'](arg1, arg2);
+export function SearchFiles(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SearchFiles'](arg1, arg2, arg3);
+}
+
+export function SearchFilesPage(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SearchFilesPage'](arg1, arg2, arg3);
 }
 
 export function SetupPassword(arg1, arg2) {
@@ -121,6 +121,66 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class SearchOptions {
+	    regex: boolean;
+	    caseSensitive: boolean;
+	    wholeWord: boolean;
+	    offset: number;
+	    limit: number;
+	    scope: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.regex = source["regex"];
+	        this.caseSensitive = source["caseSensitive"];
+	        this.wholeWord = source["wholeWord"];
+	        this.offset = source["offset"];
+	        this.limit = source["limit"];
+	        this.scope = source["scope"];
+	    }
+	}
+	export class SearchPage {
+	    results: SearchResult[];
+	    offset: number;
+	    total: number;
+	    hasMore: boolean;
+	    truncated: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchPage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.results = this.convertValues(source["results"], SearchResult);
+	        this.offset = source["offset"];
+	        this.total = source["total"];
+	        this.hasMore = source["hasMore"];
+	        this.truncated = source["truncated"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class SearchResult {
 	    path: string;
 	    name: string;
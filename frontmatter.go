@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type NoteProperty struct {
+	Key   string      `json:"key"`
+	Type  string      `json:"type"`  // "text", "number", "bool", "date", "list", "object" or "null"
+	Value interface{} `json:"value"` // a date is kept as written, eg: "2026-10-18"
+}
+
+// NoteMeta holds the properties of a note shown with it in the file tree and the search results
+type NoteMeta struct {
+	Title string   `json:"title,omitempty"`
+	Tags  []string `json:"tags,omitempty"` // lowercased, without "#"
+	Date  string   `json:"date,omitempty"`
+}
+
+// frontMatter is the YAML block at the start of a note
+type frontMatter struct {
+	lines   []mdLine
+	end     int        // index of the line closing the block, -1 when the note has none
+	mapping *yaml.Node // the properties, nil when the block is empty
+}
+
+/**
+ * --- Front matter
+ */
+// parseFrontMatter parses the front matter of a note, a block that isn't a YAML mapping is "invalid_frontmatter"
+func parseFrontMatter(content string) (frontMatter, error) {
+	fm := frontMatter{lines: splitMarkdownLines(content)}
+	fm.end = frontmatterEnd(fm.lines)
+	if fm.end == -1 {
+		return fm, nil
+	}
+
+	var doc yaml.Node
+	err := yaml.Unmarshal([]byte(content[fm.lines[0].next:fm.lines[fm.end].start]), &doc)
+	if err != nil {
+		return fm, fmt.Errorf("invalid_frontmatter")
+	}
+	if len(doc.Content) == 0 {
+		return fm, nil
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return fm, fmt.Errorf("invalid_frontmatter")
+	}
+	fm.mapping = doc.Content[0]
+
+	return fm, nil
+}
+
+// properties returns the typed properties in the order of the block
+func (fm frontMatter) properties() []NoteProperty {
+	properties := []NoteProperty{}
+	if fm.mapping == nil {
+		return properties
+	}
+	for i := 0; i+1 < len(fm.mapping.Content); i += 2 {
+		properties = append(properties, noteProperty(fm.mapping.Content[i].Value, fm.mapping.Content[i+1]))
+	}
+	return properties
+}
+
+// noteProperty types the value of a property from its YAML tag
+func noteProperty(key string, node *yaml.Node) NoteProperty {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	property := NoteProperty{Key: key, Type: "text"}
+	switch node.Kind {
+	case yaml.SequenceNode:
+		property.Type = "list"
+	case yaml.MappingNode:
+		property.Type = "object"
+	default:
+		switch node.ShortTag() {
+		case "!!int", "!!float":
+			property.Type = "number"
+		case "!!bool":
+			property.Type = "bool"
+		case "!!null":
+			property.Type = "null"
+		case "!!timestamp":
+			property.Type, property.Value = "date", node.Value
+			return property
+		}
+	}
+
+	var value interface{}
+	if node.Decode(&value) != nil {
+		property.Type, property.Value = "text", node.Value
+		return property
+	}
+	if number, ok := value.(float64); ok && (math.IsInf(number, 0) || math.IsNaN(number)) {
+		property.Type, value = "text", node.Value // not representable in JSON
+	}
+	property.Value = value
+
+	return property
+}
+
+// keyLines returns the lines of the block holding a property, from its key to the next one
+// blank lines and comments in front of the next key are left to it
+func (fm frontMatter) keyLines(i int) (int, int) {
+	// node lines are counted from the block, the block starts on the second line of the note
+	start := fm.mapping.Content[i].Line
+	end := fm.end
+	if i+2 < len(fm.mapping.Content) {
+		end = fm.mapping.Content[i+2].Line
+	}
+	for end-1 > start && (fm.lines[end-1].text == "" || strings.HasPrefix(fm.lines[end-1].text, "#")) {
+		end--
+	}
+	return start, end
+}
+
+// propertyYAML returns the YAML lines of a property, a "YYYY-MM-DD" string is written as a date
+func propertyYAML(key string, value interface{}) (string, error) {
+	var node yaml.Node
+	err := node.Encode(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid_property")
+	}
+	if text, ok := value.(string); ok {
+		if _, err := time.Parse(time.DateOnly, text); err == nil {
+			node.Tag, node.Style = "!!timestamp", 0
+		}
+	}
+	mapping := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{{Kind: yaml.ScalarNode, Value: key}, &node}}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	err = encoder.Encode(mapping)
+	if err != nil {
+		return "", fmt.Errorf("invalid_property")
+	}
+	encoder.Close()
+
+	return out.String(), nil
+}
+
+// set returns the content with a property set, or removed when remove is true
+// only the lines of the property change, the rest of the note is kept as it is
+func (fm frontMatter) set(content, key string, value interface{}, remove bool) (string, error) {
+	entry := ""
+	if !remove {
+		var err error
+		entry, err = propertyYAML(key, value)
+		if err != nil {
+			return "", err
+		}
+	}
+	newline := "\n"
+	if len(fm.lines) > 0 && strings.HasSuffix(content[:fm.lines[0].next], "\r\n") {
+		newline = "\r\n"
+	}
+	entry = strings.ReplaceAll(entry, "\n", newline)
+
+	if fm.end == -1 {
+		if remove {
+			return content, nil
+		}
+		return "---" + newline + entry + "---" + newline + content, nil
+	}
+
+	if fm.mapping != nil && fm.mapping.Style&yaml.FlowStyle != 0 {
+		return "", fmt.Errorf("invalid_frontmatter") // eg: "{a: 1}", properties don't have their own lines
+	}
+
+	start, end := fm.end, fm.end
+	if fm.mapping != nil {
+		for i := 0; i+1 < len(fm.mapping.Content); i += 2 {
+			if fm.mapping.Content[i].Value == key {
+				start, end = fm.keyLines(i)
+				break
+			}
+		}
+	}
+	if start == end && remove {
+		return content, nil // no such property
+	}
+
+	return content[:fm.lines[start].start] + entry + content[fm.lines[end].start:], nil
+}
+
+// noteMeta returns the title, tags and date properties of a note, nil when it has none
+func noteMeta(content string) *NoteMeta {
+	if !strings.HasPrefix(content, "---") {
+		return nil
+	}
+	fm, err := parseFrontMatter(content)
+	if err != nil {
+		return nil
+	}
+
+	meta := &NoteMeta{}
+	for _, property := range fm.properties() {
+		switch strings.ToLower(property.Key) {
+		case "title":
+			if property.Type == "text" || property.Type == "number" {
+				meta.Title = fmt.Sprint(property.Value)
+			}
+		case "tags", "tag":
+			meta.Tags = propertyTags(property.Value)
+		case "date":
+			if property.Type == "date" || property.Type == "text" {
+				meta.Date = fmt.Sprint(property.Value)
+			}
+		}
+	}
+	if meta.Title == "" && len(meta.Tags) == 0 && meta.Date == "" {
+		return nil
+	}
+	return meta
+}
+
+// propertyTags returns the tags of a "tags" property, a list or a string of tags separated by commas or spaces
+func propertyTags(value interface{}) []string {
+	var values []string
+	switch value := value.(type) {
+	case []interface{}:
+		for _, item := range value {
+			if item != nil {
+				values = append(values, fmt.Sprint(item))
+			}
+		}
+	case string:
+		values = strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+	}
+
+	var tags []string
+	for _, tag := range values {
+		tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// GetNoteProperties returns the properties of the front matter of a note
+func (a *App) GetNoteProperties(path string) ([]NoteProperty, error) {
+	content, err := a.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fm, err := parseFrontMatter(content)
+	if err != nil {
+		return nil, err
+	}
+	return fm.properties(), nil
+}
+
+// SetNoteProperty sets a property of the front matter of a note, the block is created when the note has none
+// the value is a string, a number, a boolean, a list or an object, a "YYYY-MM-DD" string is a date
+func (a *App) SetNoteProperty(path string, key string, value interface{}) error {
+	return a.editNoteProperty(path, key, value, false)
+}
+
+// RemoveNoteProperty removes a property from the front matter of a note
+func (a *App) RemoveNoteProperty(path string, key string) error {
+	return a.editNoteProperty(path, key, nil, true)
+}
+
+// editNoteProperty sets or removes a property, through WriteContentInFile so the change is in the note history
+func (a *App) editNoteProperty(path string, key string, value interface{}, remove bool) error {
+	if strings.TrimSpace(key) == "" {
+		return fmt.Errorf("invalid_property")
+	}
+	content, err := a.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	fm, err := parseFrontMatter(content)
+	if err != nil {
+		return err
+	}
+
+	updated, err := fm.set(content, key, value, remove)
+	if err != nil || updated == content {
+		return err
+	}
+	return a.WriteContentInFile(path, updated)
+}
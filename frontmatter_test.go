@@ -0,0 +1,121 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGetNoteProperties(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+	a.WriteContentInFile(note, "---\ntitle: Plan\ncount: 3\ndone: false\ndate: 2026-10-18\ntags: [a, b]\nowner:\n  name: jane\nempty:\n---\n# Plan\n")
+
+	properties, err := a.GetNoteProperties(note)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct{ key, kind string }{
+		{"title", "text"}, {"count", "number"}, {"done", "bool"}, {"date", "date"},
+		{"tags", "list"}, {"owner", "object"}, {"empty", "null"},
+	}
+	if len(properties) != len(expected) {
+		t.Fatalf("unexpected properties %+v", properties)
+	}
+	for i, property := range properties {
+		if property.Key != expected[i].key || property.Type != expected[i].kind {
+			t.Fatalf("property %d: expected %+v, got %+v", i, expected[i], property)
+		}
+	}
+	if properties[1].Value != 3 || properties[3].Value != "2026-10-18" || len(properties[4].Value.([]interface{})) != 2 {
+		t.Fatalf("unexpected values %+v", properties)
+	}
+
+	a.WriteContentInFile(note, "no front matter")
+	if properties, _ := a.GetNoteProperties(note); len(properties) != 0 {
+		t.Fatal("a note without front matter has no properties")
+	}
+	a.WriteContentInFile(note, "---\n- a list\n---\n")
+	if _, err := a.GetNoteProperties(note); err == nil || err.Error() != "invalid_frontmatter" {
+		t.Fatalf("expected an invalid_frontmatter error, got %v", err)
+	}
+}
+
+func TestSetNoteProperty(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+	a.WriteContentInFile(note, "---\r\ntitle: Old # kept comment\r\ntags:\r\n  - a\r\n# about the date\r\ndate: 2026-01-01\r\n---\r\nbody\r\n---\r\n")
+
+	if err := a.SetNoteProperty(note, "tags", []interface{}{"x", "z"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetNoteProperty(note, "date", "2026-10-18"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetNoteProperty(note, "rating", 4.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.RemoveNoteProperty(note, "title"); err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := a.ReadFile(note)
+	expected := "---\r\ntags:\r\n  - x\r\n  - z\r\n# about the date\r\ndate: 2026-10-18\r\nrating: 4.5\r\n---\r\nbody\r\n---\r\n"
+	if content != expected {
+		t.Fatalf("expected %q, got %q", expected, content)
+	}
+	if properties, _ := a.GetNoteProperties(note); properties[1].Type != "date" {
+		t.Fatalf("a YYYY-MM-DD string must be written as a date, got %+v", properties[1])
+	}
+
+	plain := filepath.Join(a.rootPath, "plain.md")
+	a.WriteContentInFile(plain, "# Title\n")
+	a.SetNoteProperty(plain, "title", "A: colon")
+	if content, _ := a.ReadFile(plain); content != "---\ntitle: 'A: colon'\n---\n# Title\n" {
+		t.Fatalf("expected a new front matter, got %q", content)
+	}
+	if err := a.SetNoteProperty(plain, " ", "x"); err == nil {
+		t.Fatal("expected an error for an empty key")
+	}
+}
+
+func TestNoteMeta(t *testing.T) {
+	meta := noteMeta("---\ntitle: Plan\ntags: \"#Work, idea\"\ndate: 2026-10-18\n---\n")
+	if meta == nil || meta.Title != "Plan" || len(meta.Tags) != 2 || meta.Tags[0] != "work" || meta.Date != "2026-10-18" {
+		t.Fatalf("unexpected meta %+v", meta)
+	}
+	if noteMeta("---\nauthor: me\n---\n") != nil || noteMeta("# no front matter") != nil {
+		t.Fatal("notes without title, tags or date have no meta")
+	}
+
+	a := newTestVault(t)
+	a.WriteContentInFile(filepath.Join(a.rootPath, "plan.md"), "---\ntitle: The plan\n---\nsteps")
+
+	ready := make(chan struct{}, 1)
+	a.emit = func(name string, data interface{}) {
+		if name == "index:ready" {
+			ready <- struct{}{}
+		}
+	}
+
+	// the first tree doesn't wait for the index, the one after "index:ready" has the properties
+	if _, err := a.GetDirectoryTree(a.rootPath); err != nil {
+		t.Fatal(err)
+	}
+	<-ready
+	tree, err := a.GetDirectoryTree(a.rootPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Children[0].Meta == nil || tree.Children[0].Meta.Title != "The plan" {
+		t.Fatalf("expected the title in the file tree, got %+v", tree.Children[0])
+	}
+
+	results, _ := a.SearchFiles(a.rootPath, "steps", SearchOptions{})
+	if len(results) != 1 || results[0].Meta == nil || results[0].Meta.Title != "The plan" {
+		t.Fatalf("expected the title in the search results, got %+v", results)
+	}
+	results, _ = a.SearchFiles(a.rootPath, "plan", SearchOptions{})
+	if len(results) != 2 || results[0].MatchType != "filename" || results[0].Meta == nil {
+		t.Fatalf("expected the title in the name matches, got %+v", results)
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const (
+	gitDefaultCommitDelay   = 30 * time.Second
+	gitDefaultCommitMessage = "tape: update {files}"
+)
+
+type GitCommit struct {
+	Hash      string `json:"hash"`
+	ShortHash string `json:"shortHash"`
+	Author    string `json:"author"`
+	Email     string `json:"email"`
+	Time      int64  `json:"time"` // unix milliseconds
+	Message   string `json:"message"`
+}
+
+// gitAutoCommit holds the paths saved since the last auto-commit and the debounce timer
+type gitAutoCommit struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	pending map[string]bool
+}
+
+/**
+ * --- Git
+ */
+// openGitRepository opens the git repository placed at the vault root
+func (a *App) openGitRepository() (*git.Repository, error) {
+	if a.rootPath == "" {
+		return nil, git.ErrRepositoryNotExists
+	}
+	return git.PlainOpen(a.rootPath)
+}
+
+// IsGitRepository check if the vault root is a git repository
+// only the opened vault is checked, a path of the frontend could point anywhere
+func (a *App) IsGitRepository() bool {
+	_, err := a.openGitRepository()
+	return err == nil
+}
+
+// SaveGitSettings saves the auto-commit settings to config
+// delay is in seconds, the message template accepts {files}, {count} and {date}
+func (a *App) SaveGitSettings(folderPath string, autoCommit bool, delay int, messageTemplate string) error {
+	config, err := a.LoadConfig(folderPath)
+	if err != nil {
+		config = &Config{}
+	}
+
+	config.GitAutoCommit = autoCommit
+	config.GitCommitDelay = delay
+	config.GitCommitMessage = messageTemplate
+	return a.SaveConfig(config, folderPath)
+}
+
+// gitRelPath returns the slash separated path relative to the repository root, "" being the root itself
+func (a *App) gitRelPath(path string) (string, error) {
+	path, err := a.resolveVaultPath(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := a.vaultRelPath(path)
+	if err != nil {
+		return "", err
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// scheduleGitCommit queues changed paths and (re)starts the debounce timer of the auto-commit
+func (a *App) scheduleGitCommit(paths ...string) {
+	config, err := a.LoadConfig(a.rootPath)
+	if err != nil || !config.GitAutoCommit || !a.IsGitRepository() {
+		return
+	}
+
+	delay := time.Duration(config.GitCommitDelay) * time.Second
+	if delay <= 0 {
+		delay = gitDefaultCommitDelay
+	}
+
+	a.git.mu.Lock()
+	defer a.git.mu.Unlock()
+
+	if a.git.pending == nil {
+		a.git.pending = make(map[string]bool)
+	}
+	for _, path := range paths {
+		rel, err := a.gitRelPath(path)
+		if err == nil && rel != "" {
+			a.git.pending[rel] = true
+		}
+	}
+
+	if a.git.timer != nil {
+		a.git.timer.Stop()
+	}
+	a.git.timer = time.AfterFunc(delay, func() {
+		a.flushGitCommit()
+	})
+}
+
+// flushGitCommit commits the pending paths right away, it is a no-op when nothing is pending
+// paths which can't be committed stay pending, eg: while the user has other changes staged
+func (a *App) flushGitCommit() error {
+	a.git.mu.Lock()
+	if a.git.timer != nil {
+		a.git.timer.Stop()
+		a.git.timer = nil
+	}
+	var paths []string
+	for path := range a.git.pending {
+		paths = append(paths, path)
+	}
+	a.git.pending = nil
+	a.git.mu.Unlock()
+
+	if len(paths) == 0 {
+		return nil
+	}
+	sort.Strings(paths)
+
+	err := a.gitCommitPaths(paths)
+	if err != nil {
+		a.git.mu.Lock()
+		if a.git.pending == nil {
+			a.git.pending = make(map[string]bool)
+		}
+		for _, path := range paths {
+			a.git.pending[path] = true
+		}
+		a.git.mu.Unlock()
+	}
+	return err
+}
+
+// gitCommitPaths stages the given paths (additions, modifications and deletions) and commits them
+// a commit holds the whole index: "git_index_not_clean" is returned rather than committing
+// the changes the user staged on other paths under a message about these ones
+func (a *App) gitCommitPaths(paths []string) error {
+	repo, err := a.openGitRepository()
+	if err != nil {
+		return err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	staged, err := gitStagedPaths(repo)
+	if err != nil {
+		return err
+	}
+	for _, name := range staged {
+		if !slices.ContainsFunc(paths, func(path string) bool { return name == path || strings.HasPrefix(name, path+"/") }) {
+			return fmt.Errorf("git_index_not_clean")
+		}
+	}
+
+	for _, path := range paths {
+		if a.IsFileExists(filepath.Join(a.rootPath, filepath.FromSlash(path))) {
+			_, err = worktree.Add(path)
+		} else {
+			err = gitRemoveFromIndex(repo, worktree, path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	config, _ := a.LoadConfig(a.rootPath)
+	message := formatGitCommitMessage(config.GitCommitMessage, paths, time.Now())
+
+	_, err = worktree.Commit(message, &git.CommitOptions{Author: gitSignature(repo)})
+	if errors.Is(err, git.ErrEmptyCommit) {
+		return nil
+	}
+	return err
+}
+
+// gitStagedPaths returns the paths whose content in the index differs from HEAD, every path of the index without commit
+func gitStagedPaths(repo *git.Repository) ([]string, error) {
+	index, err := repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+
+	var tree *object.Tree
+	head, err := repo.Head()
+	if err == nil {
+		commit, err := repo.CommitObject(head.Hash())
+		if err != nil {
+			return nil, err
+		}
+		tree, err = commit.Tree()
+		if err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, err
+	}
+
+	var staged []string
+	indexed := make(map[string]bool)
+	for _, entry := range index.Entries {
+		indexed[entry.Name] = true
+		if tree == nil {
+			staged = append(staged, entry.Name)
+			continue
+		}
+		committed, err := tree.FindEntry(entry.Name)
+		if err != nil || committed.Hash != entry.Hash || committed.Mode != entry.Mode {
+			staged = append(staged, entry.Name) // added or modified
+		}
+	}
+	if tree == nil {
+		return staged, nil
+	}
+
+	err = tree.Files().ForEach(func(file *object.File) error {
+		if !indexed[file.Name] {
+			staged = append(staged, file.Name) // removed
+		}
+		return nil
+	})
+	return staged, err
+}
+
+// gitRemoveFromIndex stages the deletion of a file or of every file of a deleted folder
+func gitRemoveFromIndex(repo *git.Repository, worktree *git.Worktree, path string) error {
+	index, err := repo.Storer.Index()
+	if err != nil {
+		return err
+	}
+
+	var tracked []string
+	for _, entry := range index.Entries {
+		if entry.Name == path || strings.HasPrefix(entry.Name, path+"/") {
+			tracked = append(tracked, entry.Name)
+		}
+	}
+
+	for _, name := range tracked {
+		_, err := worktree.Remove(name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatGitCommitMessage fills the commit message template
+func formatGitCommitMessage(template string, paths []string, at time.Time) string {
+	if template == "" {
+		template = gitDefaultCommitMessage
+	}
+	replacer := strings.NewReplacer(
+		"{files}", strings.Join(paths, ", "),
+		"{count}", strconv.Itoa(len(paths)),
+		"{date}", at.Format(time.RFC3339),
+	)
+	return replacer.Replace(template)
+}
+
+// gitSignature returns the author configured for the repository, falling back on a tape identity
+func gitSignature(repo *git.Repository) *object.Signature {
+	signature := &object.Signature{Name: "tape", Email: "tape@localhost", When: time.Now()}
+
+	config, err := repo.ConfigScoped(gitconfig.GlobalScope)
+	if err != nil {
+		return signature
+	}
+	if config.User.Name != "" {
+		signature.Name = config.User.Name
+	}
+	if config.User.Email != "" {
+		signature.Email = config.User.Email
+	}
+	return signature
+}
+
+// GitLog returns the commits touching a note or a folder, newest first
+func (a *App) GitLog(path string) ([]GitCommit, error) {
+	rel, err := a.gitRelPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := a.openGitRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	options := &git.LogOptions{Order: git.LogOrderCommitterTime}
+	if rel != "" {
+		options.PathFilter = func(name string) bool {
+			return name == rel || strings.HasPrefix(name, rel+"/")
+		}
+	}
+
+	iter, err := repo.Log(options)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return []GitCommit{}, nil // no commit yet
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	commits := []GitCommit{}
+	err = iter.ForEach(func(commit *object.Commit) error {
+		commits = append(commits, GitCommit{
+			Hash:      commit.Hash.String(),
+			ShortHash: commit.Hash.String()[:7],
+			Author:    commit.Author.Name,
+			Email:     commit.Author.Email,
+			Time:      commit.Author.When.UnixMilli(),
+			Message:   strings.TrimSpace(commit.Message),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return commits, nil
+}
+
+// GitShow returns the content of a note at a revision, decrypted in privacy mode
+func (a *App) GitShow(path string, rev string) (string, error) {
+	rel, err := a.gitRelPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	repo, err := a.openGitRepository()
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return "", err
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return "", err
+	}
+	file, err := commit.File(rel)
+	if err != nil {
+		return "", err
+	}
+	content, err := file.Contents()
+	if err != nil {
+		return "", err
+	}
+
+	if content != "" && a.HasSecurity(a.rootPath) && isMDE(rel) {
+		text, err := a.decryptMDE1([]byte(content), false)
+		if err != nil {
+			return "", err
+		}
+		return string(text), nil
+	}
+
+	return content, nil
+}
+
+// GitDiff returns the diff of a note between two revisions
+// an empty revB compares with the content on disk, a note missing from a revision is compared as empty
+func (a *App) GitDiff(path string, revA string, revB string) (Diff, error) {
+	contentA, err := a.GitShow(path, revA)
+	if err != nil && !errors.Is(err, object.ErrFileNotFound) {
+		return Diff{}, err
+	}
+
+	var contentB string
+	if revB == "" {
+		contentB, err = a.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return Diff{}, err
+		}
+	} else {
+		contentB, err = a.GitShow(path, revB)
+		if err != nil && !errors.Is(err, object.ErrFileNotFound) {
+			return Diff{}, err
+		}
+	}
+
+	return a.GetContentDiff(contentA, contentB), nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// newTestGitVault returns an App opened on a fresh git repository with auto-commit enabled
+func newTestGitVault(t *testing.T) *App {
+	t.Helper()
+	a := newTestVault(t)
+	if _, err := git.PlainInit(a.rootPath, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SaveGitSettings(a.rootPath, true, 3600, "notes: {count} changed"); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestIsGitRepository(t *testing.T) {
+	a := newTestVault(t)
+	if a.IsGitRepository() {
+		t.Fatal("a new vault is not a git repository")
+	}
+	git.PlainInit(a.rootPath, false)
+	if !a.IsGitRepository() {
+		t.Fatal("expected the vault root to be a git repository")
+	}
+}
+
+func TestGitAutoCommitOnSave(t *testing.T) {
+	a := newTestGitVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+
+	a.WriteContentInFile(note, "first")
+	if err := a.flushGitCommit(); err != nil {
+		t.Fatal(err)
+	}
+	a.WriteContentInFile(note, "second")
+	a.WriteContentInFile(note, "third") // debounced with the previous save
+	if err := a.flushGitCommit(); err != nil {
+		t.Fatal(err)
+	}
+
+	commits, err := a.GitLog(note)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("expected 2 commits, got %d", len(commits))
+	}
+	if commits[0].Message != "notes: 1 changed" {
+		t.Fatalf("unexpected commit message %q", commits[0].Message)
+	}
+
+	content, err := a.GitShow(note, commits[1].Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "first" {
+		t.Fatalf("expected first content, got %q", content)
+	}
+
+	diff, err := a.GitDiff(note, commits[1].Hash, commits[0].Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.DiffString == "" {
+		t.Fatal("expected a diff between both commits")
+	}
+
+	diff, err = a.GitDiff(note, "HEAD", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.DiffString != "=5" {
+		t.Fatalf("expected no change against the working tree, got %q", diff.DiffString)
+	}
+}
+
+func TestGitAutoCommitDeletion(t *testing.T) {
+	a := newTestGitVault(t)
+	dir, _ := a.CreateDirectory(a.rootPath, "folder")
+	note := filepath.Join(dir, "note.md")
+	a.WriteContentInFile(note, "content")
+	a.flushGitCommit()
+
+	if err := a.DeleteDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.flushGitCommit(); err != nil {
+		t.Fatal(err)
+	}
+
+	commits, _ := a.GitLog(a.rootPath)
+	if len(commits) != 2 {
+		t.Fatalf("expected the deletion to be committed, got %d commits", len(commits))
+	}
+	if _, err := a.GitShow(note, "HEAD"); err == nil {
+		t.Fatal("note must not exist in HEAD anymore")
+	}
+}
+
+func TestGitAutoCommitKeepsUserIndex(t *testing.T) {
+	a := newTestGitVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+	a.WriteContentInFile(note, "first")
+	a.flushGitCommit()
+
+	// staged by the user, not through tape
+	repo, _ := git.PlainOpen(a.rootPath)
+	worktree, _ := repo.Worktree()
+	os.WriteFile(filepath.Join(a.rootPath, "draft.md"), []byte("draft"), 0600)
+	if _, err := worktree.Add("draft.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	a.WriteContentInFile(note, "second")
+	if err := a.flushGitCommit(); err == nil || err.Error() != "git_index_not_clean" {
+		t.Fatalf("expected git_index_not_clean, got %v", err)
+	}
+	if commits, _ := a.GitLog(a.rootPath); len(commits) != 1 {
+		t.Fatalf("nothing must be committed over the user's index, got %d commits", len(commits))
+	}
+
+	// once the user commits their change, the saved note is committed alone
+	if _, err := worktree.Commit("draft", &git.CommitOptions{Author: gitSignature(repo)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.flushGitCommit(); err != nil {
+		t.Fatal(err)
+	}
+	commits, _ := a.GitLog(a.rootPath)
+	if len(commits) != 3 {
+		t.Fatalf("expected the pending note to be committed, got %d commits", len(commits))
+	}
+	commit, _ := repo.CommitObject(plumbing.NewHash(commits[0].Hash))
+	stats, _ := commit.Stats()
+	if len(stats) != 1 || stats[0].Name != "note.md" {
+		t.Fatalf("expected only note.md in the auto-commit, got %v", stats)
+	}
+}
+
+func TestGitAutoCommitDisabled(t *testing.T) {
+	a := newTestGitVault(t)
+	a.SaveGitSettings(a.rootPath, false, 0, "")
+
+	a.WriteContentInFile(filepath.Join(a.rootPath, "note.md"), "content")
+	a.flushGitCommit()
+
+	commits, err := a.GitLog(a.rootPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 0 {
+		t.Fatalf("expected no commit, got %d", len(commits))
+	}
+}
+
+func TestFormatGitCommitMessage(t *testing.T) {
+	at := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+
+	message := formatGitCommitMessage("", []string{"a.md", "b/c.md"}, at)
+	if message != "tape: update a.md, b/c.md" {
+		t.Fatalf("unexpected default message %q", message)
+	}
+
+	message = formatGitCommitMessage("{count} notes on {date}", []string{"a.md"}, at)
+	if !strings.HasPrefix(message, "1 notes on 2026-10-18T09:30:00") {
+		t.Fatalf("unexpected message %q", message)
+	}
+}
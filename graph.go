@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+type GraphFilter struct {
+	Folder string   `json:"folder"` // only the notes of this folder and its subfolders, the whole vault when empty
+	Tags   []string `json:"tags"`   // only the notes with one of these tags or their subtags, eg: "project" keeps "#project/tape"
+}
+
+type GraphNode struct {
+	ID     string `json:"id"`     // "note:<path in the vault>" or "tag:<tag>"
+	Kind   string `json:"kind"`   // "note" or "tag"
+	Label  string `json:"label"`  // name of the note or "#tag"
+	Path   string `json:"path"`   // the note, empty for a tag
+	Weight int    `json:"weight"` // links, embeds and tags from and to the node
+}
+
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Kind   string `json:"kind"`   // "link", "embed" or "tag"
+	Weight int    `json:"weight"` // number of such links from the source to the target
+}
+
+type VaultGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+/**
+ * --- Graph
+ */
+// GetVaultGraph returns the notes and tags of the vault as nodes, and the links, embeds and tags as edges
+// names are the decrypted ones in privacy mode, a locked vault has an empty graph
+func (a *App) GetVaultGraph(rootPath string, filter GraphFilter) (VaultGraph, error) {
+	graph := VaultGraph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	root, err := a.resolveVaultPath(rootPath)
+	if err != nil {
+		return graph, err
+	}
+	if a.HasSecurity(root) && a.masterkey == nil {
+		return graph, nil
+	}
+
+	folder := ""
+	if filter.Folder != "" {
+		resolved, err := a.resolveVaultPath(filter.Folder)
+		if err != nil {
+			return graph, err
+		}
+		folder, _ = filepath.Rel(root, resolved)
+	}
+
+	docs := a.listVaultDocs(root)
+	resolver := a.newLinkResolver(docs)
+	nodes := make(map[string]*GraphNode)
+	edges := make(map[GraphEdge]int) // edge without weight => weight
+	contents := make(map[string]string)
+
+	// the filters select the notes first, links are kept between selected notes only
+	for _, doc := range docs {
+		if doc.IsDir || (folder != "" && folder != "." && doc.Path != folder && !strings.HasPrefix(doc.Path, folder+string(filepath.Separator))) {
+			continue
+		}
+		content, err := a.ReadFile(filepath.Join(root, doc.Path))
+		if err != nil {
+			continue
+		}
+		if len(filter.Tags) > 0 && !hasAnyTag(noteTags(content), filter.Tags) {
+			continue
+		}
+		contents[doc.Path] = content
+		id := "note:" + resolver.shown[doc.Path]
+		nodes[id] = &GraphNode{ID: id, Kind: "note", Label: doc.Name, Path: filepath.Join(root, doc.Path)}
+	}
+
+	for rel, content := range contents {
+		source := "note:" + resolver.shown[rel]
+		for _, ref := range parseLinks(content) {
+			target, ok := resolver.resolve(rel, ref.target, ref.kind)
+			if _, selected := contents[target]; !ok || !selected || target == rel {
+				continue
+			}
+			kind := "link"
+			if ref.embed {
+				kind = "embed"
+			}
+			edges[GraphEdge{Source: source, Target: "note:" + resolver.shown[target], Kind: kind}]++
+		}
+
+		for _, tag := range noteTags(content) {
+			id := "tag:" + tag
+			if nodes[id] == nil {
+				nodes[id] = &GraphNode{ID: id, Kind: "tag", Label: "#" + tag}
+			}
+			edges[GraphEdge{Source: source, Target: id, Kind: "tag"}]++
+		}
+	}
+
+	for edge, weight := range edges {
+		edge.Weight = weight
+		graph.Edges = append(graph.Edges, edge)
+		nodes[edge.Source].Weight += weight
+		nodes[edge.Target].Weight += weight
+	}
+	for _, node := range nodes {
+		graph.Nodes = append(graph.Nodes, *node)
+	}
+
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		return graph.Nodes[i].ID < graph.Nodes[j].ID
+	})
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		x, y := graph.Edges[i], graph.Edges[j]
+		if x.Source != y.Source {
+			return x.Source < y.Source
+		}
+		if x.Target != y.Target {
+			return x.Target < y.Target
+		}
+		return x.Kind < y.Kind
+	})
+
+	return graph, nil
+}
+
+// graphML returns the graph in the GraphML format
+func (graph VaultGraph) graphML() string {
+	escape := func(text string) string {
+		var out bytes.Buffer
+		xml.EscapeText(&out, []byte(text))
+		return out.String()
+	}
+
+	var out strings.Builder
+	out.WriteString(xml.Header)
+	out.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	out.WriteString(`  <key id="kind" for="all" attr.name="kind" attr.type="string"/>` + "\n")
+	out.WriteString(`  <key id="label" for="node" attr.name="label" attr.type="string"/>` + "\n")
+	out.WriteString(`  <key id="weight" for="all" attr.name="weight" attr.type="int"/>` + "\n")
+	out.WriteString(`  <graph id="vault" edgedefault="directed">` + "\n")
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(&out, "    <node id=\"%s\">\n", escape(node.ID))
+		fmt.Fprintf(&out, "      <data key=\"kind\">%s</data>\n", node.Kind)
+		fmt.Fprintf(&out, "      <data key=\"label\">%s</data>\n", escape(node.Label))
+		fmt.Fprintf(&out, "      <data key=\"weight\">%d</data>\n", node.Weight)
+		out.WriteString("    </node>\n")
+	}
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(&out, "    <edge source=\"%s\" target=\"%s\">\n", escape(edge.Source), escape(edge.Target))
+		fmt.Fprintf(&out, "      <data key=\"kind\">%s</data>\n", edge.Kind)
+		fmt.Fprintf(&out, "      <data key=\"weight\">%d</data>\n", edge.Weight)
+		out.WriteString("    </edge>\n")
+	}
+	out.WriteString("  </graph>\n</graphml>\n")
+
+	return out.String()
+}
+
+// dot returns the graph in the Graphviz DOT format, tags are boxes and embeds dashed edges
+func (graph VaultGraph) dot() string {
+	var out strings.Builder
+	out.WriteString("digraph vault {\n")
+	for _, node := range graph.Nodes {
+		shape := "ellipse"
+		if node.Kind == "tag" {
+			shape = "box"
+		}
+		fmt.Fprintf(&out, "  %s [label=%s, shape=%s, weight=%d];\n", strconv.Quote(node.ID), strconv.Quote(node.Label), shape, node.Weight)
+	}
+	for _, edge := range graph.Edges {
+		style := "solid"
+		switch edge.Kind {
+		case "embed":
+			style = "dashed"
+		case "tag":
+			style = "dotted"
+		}
+		fmt.Fprintf(&out, "  %s -> %s [weight=%d, penwidth=%d, style=%s];\n", strconv.Quote(edge.Source), strconv.Quote(edge.Target), edge.Weight, min(edge.Weight, 5), style)
+	}
+	out.WriteString("}\n")
+
+	return out.String()
+}
+
+// ExportVaultGraph returns the graph of the vault in the "graphml" or "dot" format
+func (a *App) ExportVaultGraph(rootPath string, filter GraphFilter, format string) (string, error) {
+	if format != "graphml" && format != "dot" {
+		return "", fmt.Errorf("invalid_format")
+	}
+	graph, err := a.GetVaultGraph(rootPath, filter)
+	if err != nil {
+		return "", err
+	}
+	if format == "dot" {
+		return graph.dot(), nil
+	}
+	return graph.graphML(), nil
+}
+
+// SaveVaultGraph asks where to save the graph of the vault and writes it in the "graphml" or "dot" format
+// it returns the path of the file, empty when the dialog was cancelled
+func (a *App) SaveVaultGraph(rootPath string, filter GraphFilter, format string) (string, error) {
+	data, err := a.ExportVaultGraph(rootPath, filter, format)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Export the graph of the vault",
+		DefaultFilename: "vault." + format,
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	return path, os.WriteFile(path, []byte(data), 0600)
+}
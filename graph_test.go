@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newGraphVault(t *testing.T) *App {
+	t.Helper()
+	a := newTestVault(t)
+	os.Mkdir(filepath.Join(a.rootPath, "projects"), 0700)
+	a.WriteContentInFile(filepath.Join(a.rootPath, "home.md"), "[[plan]] and [[plan#Goals]], ![[diagram]] #index")
+	a.WriteContentInFile(filepath.Join(a.rootPath, "projects", "plan.md"), "[home](../home.md) #project/tape")
+	a.WriteContentInFile(filepath.Join(a.rootPath, "projects", "diagram.md"), "[[missing]] #project")
+	return a
+}
+
+func TestGetVaultGraph(t *testing.T) {
+	a := newGraphVault(t)
+
+	graph, err := a.GetVaultGraph(a.rootPath, GraphFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, node := range graph.Nodes {
+		ids = append(ids, node.ID)
+	}
+	if strings.Join(ids, ",") != "note:home.md,note:projects/diagram.md,note:projects/plan.md,tag:index,tag:project,tag:project/tape" {
+		t.Fatalf("unexpected nodes %v", ids)
+	}
+
+	expected := []GraphEdge{
+		{"note:home.md", "note:projects/diagram.md", "embed", 1},
+		{"note:home.md", "note:projects/plan.md", "link", 2},
+		{"note:home.md", "tag:index", "tag", 1},
+		{"note:projects/diagram.md", "tag:project", "tag", 1},
+		{"note:projects/plan.md", "note:home.md", "link", 1},
+		{"note:projects/plan.md", "tag:project/tape", "tag", 1},
+	}
+	if len(graph.Edges) != len(expected) {
+		t.Fatalf("unexpected edges %+v", graph.Edges)
+	}
+	for i, edge := range graph.Edges {
+		if edge != expected[i] {
+			t.Fatalf("edge %d: expected %+v, got %+v", i, expected[i], edge)
+		}
+	}
+	if graph.Nodes[0].Weight != 5 || graph.Nodes[0].Label != "home.md" || graph.Nodes[0].Path != filepath.Join(a.rootPath, "home.md") {
+		t.Fatalf("unexpected note node %+v", graph.Nodes[0])
+	}
+}
+
+func TestGetVaultGraphFilters(t *testing.T) {
+	a := newGraphVault(t)
+
+	graph, _ := a.GetVaultGraph(a.rootPath, GraphFilter{Folder: filepath.Join(a.rootPath, "projects")})
+	if len(graph.Nodes) != 4 || len(graph.Edges) != 2 {
+		t.Fatalf("links out of the folder must be left out, got %+v", graph)
+	}
+
+	graph, _ = a.GetVaultGraph(a.rootPath, GraphFilter{Tags: []string{"#Project"}})
+	if len(graph.Nodes) != 4 || graph.Nodes[0].ID != "note:projects/diagram.md" || len(graph.Edges) != 2 {
+		t.Fatalf("the tag filter must keep the notes with the tag or a subtag, got %+v", graph)
+	}
+
+	if _, err := a.GetVaultGraph(a.rootPath, GraphFilter{Folder: filepath.Join(a.rootPath, "..")}); err == nil {
+		t.Fatal("expected an error for a folder out of the vault")
+	}
+}
+
+func TestExportVaultGraph(t *testing.T) {
+	a := newGraphVault(t)
+	a.WriteContentInFile(filepath.Join(a.rootPath, "a & b's.md"), "[[home]]")
+
+	graphML, err := a.ExportVaultGraph(a.rootPath, GraphFilter{}, "graphml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed struct {
+		Graph struct {
+			Nodes []struct {
+				ID string `xml:"id,attr"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	if err := xml.Unmarshal([]byte(graphML), &parsed); err != nil {
+		t.Fatalf("invalid GraphML: %v", err)
+	}
+	if len(parsed.Graph.Nodes) != 7 || len(parsed.Graph.Edges) != 7 || parsed.Graph.Nodes[0].ID != "note:a & b's.md" {
+		t.Fatalf("unexpected GraphML %+v", parsed)
+	}
+
+	dot, _ := a.ExportVaultGraph(a.rootPath, GraphFilter{}, "dot")
+	if !strings.HasPrefix(dot, "digraph vault {\n") || !strings.Contains(dot, `"note:a & b's.md" -> "note:home.md" [weight=1`) ||
+		!strings.Contains(dot, `"note:home.md" -> "note:projects/diagram.md" [weight=1, penwidth=1, style=dashed]`) {
+		t.Fatalf("unexpected DOT graph\n%s", dot)
+	}
+
+	if _, err := a.ExportVaultGraph(a.rootPath, GraphFilter{}, "svg"); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestGetVaultGraphEncrypted(t *testing.T) {
+	a := newTestVault(t)
+	if response := a.SetupPassword("testpassword", a.rootPath); response != "ok" {
+		t.Fatal(response)
+	}
+	plan, _ := a.CreateFile(a.rootPath, "Plan")
+	home, _ := a.CreateFile(a.rootPath, "Home")
+	a.WriteContentInFile(home, "[[Plan]]")
+
+	graph, _ := a.GetVaultGraph(a.rootPath, GraphFilter{})
+	if len(graph.Edges) != 1 || graph.Edges[0].Source != "note:Home.mde" || graph.Edges[0].Target != "note:Plan.mde" || graph.Nodes[1].Path != plan {
+		t.Fatalf("the graph must use the decrypted names, got %+v", graph)
+	}
+}
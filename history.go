@@ -0,0 +1,407 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	historyKeepRecent = 50                  // the newest snapshots of a note are always kept
+	historyKeepDaily  = 30 * 24 * time.Hour // older snapshots are thinned to one per day up to this age
+)
+
+type NoteVersion struct {
+	ID   string `json:"id"`
+	Time int64  `json:"time"` // unix milliseconds
+	Size int    `json:"size"`
+	Hash string `json:"hash"`
+}
+
+type NoteVersionContent struct {
+	Version NoteVersion `json:"version"`
+	Content string      `json:"content"`
+	Diff    Diff        `json:"diff"` // changes compared to the previous version
+}
+
+type noteHistory struct {
+	Versions []NoteVersion `json:"versions"` // oldest first
+}
+
+/**
+ * --- Vault data
+ */
+// getTapeDir returns the vault-local .tape directory used to store tape data
+func (a *App) getTapeDir() string {
+	return filepath.Join(a.rootPath, ".tape")
+}
+
+// vaultRelPath returns the path relative to the vault root, the path must be already resolved
+func (a *App) vaultRelPath(path string) (string, error) {
+	root, err := filepath.Abs(a.rootPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(root, path)
+}
+
+// encryptMDE1 encrypts data with the masterkey and returns it in the MDE1 file format
+func (a *App) encryptMDE1(data []byte) ([]byte, error) {
+	nonce, cipher, err := a.encryptData(a.masterkey, data)
+	if err != nil {
+		return nil, err
+	}
+	payload := append([]byte(a.cryptVersionMDE1), nonce...)
+	return append(payload, cipher...), nil
+}
+
+// writeVaultData writes tape data inside the vault, encrypted when privacy mode is set up
+func (a *App) writeVaultData(path string, data []byte) error {
+	hasSecurity, err := a.securityOf(a.rootPath)
+	if err != nil {
+		return err
+	}
+	if hasSecurity {
+		encrypted, err := a.encryptMDE1(data)
+		if err != nil {
+			return err
+		}
+		data = encrypted
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// readVaultData reads tape data written by writeVaultData
+func (a *App) readVaultData(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if a.HasSecurity(a.rootPath) {
+		return a.decryptMDE1(data, false)
+	}
+	return data, nil
+}
+
+// hashVaultData returns a content hash, keyed with the masterkey in privacy mode
+// so stored hashes can't be matched against known contents
+func (a *App) hashVaultData(data []byte) string {
+	if a.HasSecurity(a.rootPath) {
+		mac := hmac.New(sha256.New, a.masterkey)
+		mac.Write(data)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+/**
+ * --- History
+ */
+// getHistoryDir returns the directory holding the snapshots of a note
+// note: it mirrors the note path so renaming a note or a folder only means renaming its mirror
+func (a *App) getHistoryDir(notePath string) (string, error) {
+	rel, err := a.vaultRelPath(notePath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(a.getTapeDir(), "history", rel), nil
+}
+
+// loadNoteHistory loads the history index of a note, an empty history is returned if none exist
+func (a *App) loadNoteHistory(historyDir string) (*noteHistory, error) {
+	indexPath := filepath.Join(historyDir, "index.json")
+	if !a.IsFileExists(indexPath) {
+		return &noteHistory{}, nil
+	}
+
+	data, err := a.readVaultData(indexPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var history noteHistory
+	err = json.Unmarshal(data, &history)
+	if err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// saveNoteHistory saves the history index of a note
+func (a *App) saveNoteHistory(historyDir string, history *noteHistory) error {
+	data, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	return a.writeVaultData(filepath.Join(historyDir, "index.json"), data)
+}
+
+// addNoteVersion stores content as the newest version unless it is identical to the latest one
+// blobs are named by their hash so identical contents are only stored once per note
+func (a *App) addNoteVersion(historyDir string, history *noteHistory, content string, at time.Time) error {
+	hash := a.hashVaultData([]byte(content))
+	if len(history.Versions) > 0 && history.Versions[len(history.Versions)-1].Hash == hash {
+		return nil
+	}
+
+	blobPath := filepath.Join(historyDir, hash)
+	if !a.IsFileExists(blobPath) {
+		err := a.writeVaultData(blobPath, []byte(content))
+		if err != nil {
+			return err
+		}
+	}
+
+	id := strconv.FormatInt(at.UnixNano(), 10)
+	if len(history.Versions) > 0 && history.Versions[len(history.Versions)-1].ID >= id {
+		// same clock tick as the previous save, keep ids unique and ordered
+		last, _ := strconv.ParseInt(history.Versions[len(history.Versions)-1].ID, 10, 64)
+		id = strconv.FormatInt(last+1, 10)
+	}
+
+	history.Versions = append(history.Versions, NoteVersion{
+		ID:   id,
+		Time: at.UnixMilli(),
+		Size: len(content),
+		Hash: hash,
+	})
+	return nil
+}
+
+// pruneNoteHistory applies the retention rules and removes blobs no version refers to anymore
+func (a *App) pruneNoteHistory(historyDir string, history *noteHistory, now time.Time) {
+	kept := retainVersions(history.Versions, now)
+	if len(kept) == len(history.Versions) {
+		return
+	}
+
+	used := make(map[string]bool)
+	for _, version := range kept {
+		used[version.Hash] = true
+	}
+	for _, version := range history.Versions {
+		if !used[version.Hash] {
+			os.Remove(filepath.Join(historyDir, version.Hash))
+		}
+	}
+
+	history.Versions = kept
+}
+
+// retainVersions returns the versions to keep: the historyKeepRecent newest ones,
+// then the newest version of each day younger than historyKeepDaily
+// versions must be sorted oldest first, the result keeps that order
+func retainVersions(versions []NoteVersion, now time.Time) []NoteVersion {
+	if len(versions) <= historyKeepRecent {
+		return versions
+	}
+
+	older := versions[:len(versions)-historyKeepRecent]
+	recent := versions[len(versions)-historyKeepRecent:]
+
+	var kept []NoteVersion
+	seenDays := make(map[string]bool)
+	for i := len(older) - 1; i >= 0; i-- {
+		at := time.UnixMilli(older[i].Time)
+		if now.Sub(at) > historyKeepDaily {
+			break
+		}
+		day := at.Format("2006-01-02")
+		if seenDays[day] {
+			continue
+		}
+		seenDays[day] = true
+		kept = append([]NoteVersion{older[i]}, kept...)
+	}
+
+	return append(kept, recent...)
+}
+
+// snapshotNote records content in the history of the note
+// when the note has no history yet, the content currently on disk is recorded first
+// note: called before the write so the previous content can still be read
+func (a *App) snapshotNote(filePath, content string) error {
+	if !isMDorMDE(filePath) {
+		return nil
+	}
+
+	historyDir, err := a.getHistoryDir(filePath)
+	if err != nil {
+		return err
+	}
+
+	history, err := a.loadNoteHistory(historyDir)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if len(history.Versions) == 0 {
+		info, err := os.Stat(filePath)
+		if err == nil && info.Size() > 0 {
+			previous, err := a.ReadFile(filePath)
+			if err == nil {
+				err = a.addNoteVersion(historyDir, history, previous, info.ModTime())
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	err = a.addNoteVersion(historyDir, history, content, now)
+	if err != nil {
+		return err
+	}
+
+	a.pruneNoteHistory(historyDir, history, now)
+	return a.saveNoteHistory(historyDir, history)
+}
+
+// readNoteVersionContent returns the content of one version of a note
+func (a *App) readNoteVersionContent(historyDir string, version NoteVersion) (string, error) {
+	data, err := a.readVaultData(filepath.Join(historyDir, version.Hash))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// findNoteVersion returns the index of the version with the given id
+func findNoteVersion(history *noteHistory, id string) (int, error) {
+	for i, version := range history.Versions {
+		if version.ID == id {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("version_not_found")
+}
+
+// ListNoteVersions returns the saved versions of a note, newest first
+func (a *App) ListNoteVersions(path string) ([]NoteVersion, error) {
+	path, err := a.resolveVaultPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	historyDir, err := a.getHistoryDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := a.loadNoteHistory(historyDir)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]NoteVersion, 0, len(history.Versions))
+	for i := len(history.Versions) - 1; i >= 0; i-- {
+		versions = append(versions, history.Versions[i])
+	}
+	return versions, nil
+}
+
+// ReadNoteVersion returns the content of a version and what changed since the previous one
+func (a *App) ReadNoteVersion(path string, id string) (NoteVersionContent, error) {
+	path, err := a.resolveVaultPath(path)
+	if err != nil {
+		return NoteVersionContent{}, err
+	}
+
+	historyDir, err := a.getHistoryDir(path)
+	if err != nil {
+		return NoteVersionContent{}, err
+	}
+
+	history, err := a.loadNoteHistory(historyDir)
+	if err != nil {
+		return NoteVersionContent{}, err
+	}
+
+	index, err := findNoteVersion(history, id)
+	if err != nil {
+		return NoteVersionContent{}, err
+	}
+
+	content, err := a.readNoteVersionContent(historyDir, history.Versions[index])
+	if err != nil {
+		return NoteVersionContent{}, err
+	}
+
+	previous := ""
+	if index > 0 {
+		previous, err = a.readNoteVersionContent(historyDir, history.Versions[index-1])
+		if err != nil {
+			return NoteVersionContent{}, err
+		}
+	}
+
+	return NoteVersionContent{
+		Version: history.Versions[index],
+		Content: content,
+		Diff:    a.GetContentDiff(previous, content),
+	}, nil
+}
+
+// RestoreNoteVersion writes back the content of a version and returns the diff from the replaced content
+// note: the restore is itself saved as a new version, so it can be undone
+func (a *App) RestoreNoteVersion(path string, id string) (Diff, error) {
+	version, err := a.ReadNoteVersion(path, id)
+	if err != nil {
+		return Diff{}, err
+	}
+
+	current, err := a.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return Diff{}, err
+	}
+
+	err = a.WriteContentInFile(path, version.Content)
+	if err != nil {
+		return Diff{}, err
+	}
+
+	return a.GetContentDiff(current, version.Content), nil
+}
+
+// moveNoteHistory follows a rename of a note or a folder in the history store
+func (a *App) moveNoteHistory(oldPath, newPath string) error {
+	oldDir, err := a.getHistoryDir(oldPath)
+	if err != nil {
+		return err
+	}
+	if !a.IsFileExists(oldDir) {
+		return nil
+	}
+
+	newDir, err := a.getHistoryDir(newPath)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(newDir), 0700)
+	if err != nil {
+		return err
+	}
+	return os.Rename(oldDir, newDir)
+}
+
+// deleteNoteHistory removes the history of a deleted note or folder
+func (a *App) deleteNoteHistory(path string) error {
+	historyDir, err := a.getHistoryDir(path)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(historyDir)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNoteHistoryRecordsSaves(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+
+	for _, content := range []string{"one", "two", "two", "three"} {
+		if err := a.WriteContentInFile(note, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	versions, err := a.ListNoteVersions(note)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("expected 3 versions (identical saves deduplicated), got %d", len(versions))
+	}
+
+	latest, err := a.ReadNoteVersion(note, versions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Content != "three" {
+		t.Fatalf("expected newest version first, got %q", latest.Content)
+	}
+	if latest.Diff.DiffString == "" {
+		t.Fatal("expected a diff against the previous version")
+	}
+}
+
+func TestNoteHistorySeedsExistingContent(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+	if err := os.WriteFile(note, []byte("written before history"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.WriteContentInFile(note, "first save"); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, _ := a.ListNoteVersions(note)
+	if len(versions) != 2 {
+		t.Fatalf("expected the content on disk to be recorded, got %d versions", len(versions))
+	}
+	oldest, _ := a.ReadNoteVersion(note, versions[1].ID)
+	if oldest.Content != "written before history" {
+		t.Fatalf("unexpected oldest content %q", oldest.Content)
+	}
+}
+
+func TestRestoreNoteVersion(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+	a.WriteContentInFile(note, "good")
+	a.WriteContentInFile(note, "bad")
+
+	versions, _ := a.ListNoteVersions(note)
+	diff, err := a.RestoreNoteVersion(note, versions[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.DiffString == "" {
+		t.Fatal("expected a diff between replaced and restored content")
+	}
+
+	content, _ := a.ReadFile(note)
+	if content != "good" {
+		t.Fatalf("expected restored content, got %q", content)
+	}
+
+	versions, _ = a.ListNoteVersions(note)
+	if len(versions) != 3 {
+		t.Fatalf("restore must be recorded as a new version, got %d versions", len(versions))
+	}
+
+	if _, err := a.ReadNoteVersion(note, "unknown"); err == nil {
+		t.Fatal("expected an error for an unknown version")
+	}
+}
+
+func TestNoteHistoryFollowsRename(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+	a.WriteContentInFile(note, "content")
+
+	newPath, err := a.RenameFile(note, a.rootPath, "renamed.md", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions, _ := a.ListNoteVersions(newPath)
+	if len(versions) != 1 {
+		t.Fatalf("expected history to follow the rename, got %d versions", len(versions))
+	}
+
+	if err := a.DeleteFile(newPath); err != nil {
+		t.Fatal(err)
+	}
+	versions, _ = a.ListNoteVersions(newPath)
+	if len(versions) != 0 {
+		t.Fatal("expected history to be removed with the note")
+	}
+}
+
+func TestNoteHistoryEncrypted(t *testing.T) {
+	a := newTestVault(t)
+	if response := a.SetupPassword("testpassword", a.rootPath); response != "ok" {
+		t.Fatal(response)
+	}
+	note := filepath.Join(a.rootPath, "MDE1note.mde")
+
+	if err := a.WriteContentInFile(note, "very secret content"); err != nil {
+		t.Fatal(err)
+	}
+
+	historyDir, _ := a.getHistoryDir(note)
+	entries, err := os.ReadDir(historyDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		raw, _ := os.ReadFile(filepath.Join(historyDir, entry.Name()))
+		if strings.Contains(string(raw), "very secret") || strings.Contains(string(raw), "versions") {
+			t.Fatalf("history file %q is stored in plain text", entry.Name())
+		}
+	}
+
+	versions, _ := a.ListNoteVersions(note)
+	version, err := a.ReadNoteVersion(note, versions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version.Content != "very secret content" {
+		t.Fatalf("unexpected decrypted content %q", version.Content)
+	}
+}
+
+func TestRetainVersions(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local)
+
+	var versions []NoteVersion
+	// two snapshots a day for 60 days, then the recent burst
+	for day := 60; day > 0; day-- {
+		for _, hour := range []int{9, 17} {
+			at := now.AddDate(0, 0, -day).Add(time.Duration(hour-12) * time.Hour)
+			versions = append(versions, NoteVersion{ID: at.String(), Time: at.UnixMilli()})
+		}
+	}
+	for i := 0; i < historyKeepRecent; i++ {
+		at := now.Add(-time.Duration(historyKeepRecent-i) * time.Minute)
+		versions = append(versions, NoteVersion{ID: at.String(), Time: at.UnixMilli()})
+	}
+
+	kept := retainVersions(versions, now)
+
+	if len(kept) != historyKeepRecent+30 {
+		t.Fatalf("expected recent versions plus one per day for 30 days, got %d", len(kept))
+	}
+	for i := 1; i < len(kept); i++ {
+		if kept[i-1].Time > kept[i].Time {
+			t.Fatal("kept versions must stay ordered oldest first")
+		}
+	}
+	if kept[len(kept)-1] != versions[len(versions)-1] {
+		t.Fatal("the newest version must always be kept")
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxLinkContext bounds the line kept around a link in the index
+const maxLinkContext = 200
+
+// indexedLink is a link written in a note, kept in the search index
+type indexedLink struct {
+	Target  string `json:"target"` // as written, eg: "Note#Heading" or "../notes/note.md"
+	Kind    string `json:"kind"`   // "wiki" or "markdown"
+	Line    int    `json:"line"`   // 1-based
+	Context string `json:"context"`
+}
+
+// noteLinkRef is a link found in the content of a note with the byte range of its target
+type noteLinkRef struct {
+	kind        string
+	target      string
+	targetStart int
+	targetEnd   int
+	line        int
+	embed       bool // written with a leading "!"
+	image       bool // points to an image rather than a note
+}
+
+type NoteLink struct {
+	Path     string `json:"path"`     // the note at the other end of the link, empty when unresolved
+	Name     string `json:"name"`     // display name of that note
+	Target   string `json:"target"`   // the link target as written
+	Kind     string `json:"kind"`     // "wiki" or "markdown"
+	Line     int    `json:"line"`     // line of the link in the note holding it
+	Context  string `json:"context"`  // that line
+	Resolved bool   `json:"resolved"` // false for links to notes that don't exist
+}
+
+// linkResolver finds the notes links point to, by path and by name, with decrypted names in privacy mode
+type linkResolver struct {
+	byPath map[string]string   // lowercased display path without extension => rel path on disk
+	byName map[string][]string // lowercased display name without extension => rel paths on disk
+	shown  map[string]string   // rel path on disk => display path
+}
+
+/**
+ * --- Links
+ */
+// parseLinks returns the wiki links and the markdown links to notes of a note, links in code are ignored
+func parseLinks(content string) []noteLinkRef {
+	var links []noteLinkRef
+	for _, ref := range scanLinks(content) {
+		if !ref.image {
+			links = append(links, ref)
+		}
+	}
+	return links
+}
+
+// scanLinks returns the links to notes and the images of a note
+func scanLinks(content string) []noteLinkRef {
+	var links []noteLinkRef
+	line, scanned := 1, 0
+
+	for _, region := range markdownRegions(content) {
+		if region.kind != mdLink {
+			continue
+		}
+		// adjacent links are merged in one region
+		for i := region.start; i < region.end; {
+			end := region.end
+			ref, ok := noteLinkRef{}, false
+
+			start := i
+			if content[i] == '!' {
+				start++ // an embed or an image
+			}
+			switch {
+			case strings.HasPrefix(content[start:end], "[["):
+				end = linkEnd(content, start, end)
+				ref, ok = parseWikiLink(content, start, end)
+			case strings.HasPrefix(content[start:end], "["):
+				end = linkEnd(content, start, end)
+				ref, ok = parseMarkdownLink(content, start, end, start > i)
+			default:
+				end = strings.IndexByte(content[i:region.end], '>') + i + 1 // an autolink
+			}
+			if end <= i {
+				break
+			}
+
+			if ok {
+				line += strings.Count(content[scanned:ref.targetStart], "\n")
+				scanned = ref.targetStart
+				ref.line = line
+				ref.embed = start > i
+				links = append(links, ref)
+			}
+			i = end
+		}
+	}
+
+	return links
+}
+
+// parseWikiLink parses [[Target#Heading|Alias]] between start and end
+func parseWikiLink(content string, start, end int) (noteLinkRef, bool) {
+	if end == -1 {
+		return noteLinkRef{}, false
+	}
+	inner := content[start+2 : end-2]
+	target := inner
+	if i := strings.IndexByte(target, '|'); i != -1 {
+		target = target[:i]
+	}
+	if strings.TrimSpace(target) == "" {
+		return noteLinkRef{}, false
+	}
+	return noteLinkRef{
+		kind:        "wiki",
+		target:      target,
+		targetStart: start + 2,
+		targetEnd:   start + 2 + len(target),
+		image:       isImageFile(linkTargetFile(target, "wiki")),
+	}, true
+}
+
+// parseMarkdownLink parses [text](target "title") or, for an image, ![alt](target) between start and end
+// links out of the vault and to other files than notes are left out
+func parseMarkdownLink(content string, start, end int, embed bool) (noteLinkRef, bool) {
+	if end == -1 {
+		return noteLinkRef{}, false
+	}
+	open := start + strings.Index(content[start:end], "](") + 2
+	inner := content[open : end-1]
+
+	targetStart := open + len(inner) - len(strings.TrimLeft(inner, " "))
+	target := strings.TrimSpace(inner)
+	if strings.HasPrefix(target, "<") {
+		close := strings.IndexByte(target, '>')
+		if close == -1 {
+			return noteLinkRef{}, false
+		}
+		target = target[1:close]
+		targetStart++
+	} else if i := strings.IndexAny(target, " \t"); i != -1 {
+		target = target[:i] // a title follows
+	}
+
+	if target == "" || strings.HasPrefix(target, "#") || strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:") || strings.HasPrefix(target, "data:") {
+		return noteLinkRef{}, false
+	}
+	file := linkTargetFile(target, "markdown")
+	if ext := path.Ext(file); !embed && ext != "" && !isMDorMDE(file) {
+		return noteLinkRef{}, false // an attachment
+	}
+	return noteLinkRef{kind: "markdown", target: target, targetStart: targetStart, targetEnd: targetStart + len(target), image: embed && !isMDorMDE(file)}, true
+}
+
+// isImageFile reports if a file is an image from its extension
+func isImageFile(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".bmp", ".avif":
+		return true
+	}
+	return false
+}
+
+// linkTargetFile returns the file part of a link target: without heading or block reference, url decoded
+func linkTargetFile(target, kind string) string {
+	if i := strings.IndexByte(target, '#'); i != -1 {
+		target = target[:i]
+	}
+	if kind == "markdown" {
+		if unescaped, err := url.PathUnescape(target); err == nil {
+			target = unescaped
+		}
+	}
+	return strings.TrimSpace(target)
+}
+
+// indexLinks returns the links of a note as kept in the search index
+func indexLinks(content string) []indexedLink {
+	refs := parseLinks(content)
+	if len(refs) == 0 {
+		return nil
+	}
+
+	lines := strings.Split(content, "\n")
+	links := make([]indexedLink, 0, len(refs))
+	for _, ref := range refs {
+		context := strings.TrimSpace(strings.TrimSuffix(lines[ref.line-1], "\r"))
+		if len(context) > maxLinkContext {
+			context = strings.ToValidUTF8(context[:maxLinkContext], "")
+		}
+		links = append(links, indexedLink{Target: ref.target, Kind: ref.kind, Line: ref.line, Context: context})
+	}
+	return links
+}
+
+// newLinkResolver maps the display paths and names of the notes of the index to their paths on disk
+func (a *App) newLinkResolver(docs []*indexedDoc) *linkResolver {
+	resolver := &linkResolver{
+		byPath: make(map[string]string),
+		byName: make(map[string][]string),
+		shown:  make(map[string]string),
+	}
+	for _, doc := range docs {
+		if doc.IsDir {
+			continue
+		}
+		shown := a.displayRelPath(doc.Path)
+		resolver.shown[doc.Path] = shown
+
+		key := strings.ToLower(stripFileExt(shown))
+		resolver.byPath[key] = doc.Path
+		name := path.Base(key)
+		resolver.byName[name] = append(resolver.byName[name], doc.Path)
+	}
+	for _, paths := range resolver.byName {
+		sort.Slice(paths, func(i, j int) bool {
+			return len(paths[i]) < len(paths[j]) || (len(paths[i]) == len(paths[j]) && paths[i] < paths[j])
+		})
+	}
+	return resolver
+}
+
+// resolve returns the rel path of the note a link of the note at source points to
+// wiki links match a path from the vault root or a note name, the closest to the source first
+// markdown links are relative to the folder of the source, or to the vault root when starting with "/"
+func (r *linkResolver) resolve(source string, target string, kind string) (string, bool) {
+	file := linkTargetFile(target, kind)
+	if file == "" {
+		// [[#Heading]] points to the note itself
+		_, known := r.shown[source]
+		return source, known && strings.HasPrefix(strings.TrimSpace(target), "#")
+	}
+	sourceDir := path.Dir(r.shown[source])
+
+	if kind == "markdown" {
+		wanted := path.Join(sourceDir, file)
+		if strings.HasPrefix(file, "/") {
+			wanted = path.Clean(file[1:])
+		}
+		if strings.HasPrefix(wanted, "../") {
+			return "", false
+		}
+		rel, ok := r.byPath[strings.ToLower(stripFileExt(wanted))]
+		return rel, ok
+	}
+
+	key := strings.ToLower(stripFileExt(strings.TrimPrefix(file, "/")))
+	if rel, ok := r.byPath[key]; ok {
+		return rel, true
+	}
+	if rel, ok := r.byPath[strings.ToLower(path.Join(sourceDir, stripFileExt(file)))]; ok {
+		return rel, true
+	}
+	if strings.Contains(key, "/") {
+		return "", false
+	}
+
+	candidates := r.byName[key]
+	for _, rel := range candidates {
+		if path.Dir(r.shown[rel]) == sourceDir {
+			return rel, true
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0], true
+	}
+	return "", false
+}
+
+// linkIndex returns the index holding the links and the rel path of a note, the note must be in the vault
+func (a *App) linkIndex(notePath string) (*searchIndex, string, error) {
+	notePath, err := a.resolveVaultPath(notePath)
+	if err != nil {
+		return nil, "", err
+	}
+	rel, err := a.vaultRelPath(notePath)
+	if err != nil {
+		return nil, "", err
+	}
+	root, _ := filepath.Abs(a.rootPath)
+	return a.searchIndexFor(root), rel, nil
+}
+
+// noteLink builds the NoteLink shown for a link with the note at its other end
+func (a *App) noteLink(rel string, resolved bool, link indexedLink) NoteLink {
+	noteLink := NoteLink{
+		Target:   link.Target,
+		Kind:     link.Kind,
+		Line:     link.Line,
+		Context:  link.Context,
+		Resolved: resolved,
+	}
+	if resolved {
+		noteLink.Path = filepath.Join(a.rootPath, rel)
+		noteLink.Name = a.displayName(noteLink.Path)
+	}
+	return noteLink
+}
+
+// GetOutgoingLinks returns the links written in a note, resolved to the notes they point to
+func (a *App) GetOutgoingLinks(notePath string) ([]NoteLink, error) {
+	index, rel, err := a.linkIndex(notePath)
+	if err != nil || index == nil {
+		return []NoteLink{}, err
+	}
+
+	docs := index.allDocs()
+	resolver := a.newLinkResolver(docs)
+	links := []NoteLink{}
+	for _, doc := range docs {
+		if doc.Path != rel {
+			continue
+		}
+		for _, link := range doc.Links {
+			target, ok := resolver.resolve(rel, link.Target, link.Kind)
+			links = append(links, a.noteLink(target, ok, link))
+		}
+	}
+	return links, nil
+}
+
+// GetBacklinks returns the links of other notes pointing to a note, Path being the note holding the link
+func (a *App) GetBacklinks(notePath string) ([]NoteLink, error) {
+	index, rel, err := a.linkIndex(notePath)
+	if err != nil || index == nil {
+		return []NoteLink{}, err
+	}
+
+	docs := index.allDocs()
+	resolver := a.newLinkResolver(docs)
+	links := []NoteLink{}
+	for _, doc := range docs {
+		for _, link := range doc.Links {
+			if target, ok := resolver.resolve(doc.Path, link.Target, link.Kind); ok && target == rel && doc.Path != rel {
+				links = append(links, a.noteLink(doc.Path, true, link))
+			}
+		}
+	}
+	return links, nil
+}
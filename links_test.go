@@ -0,0 +1,157 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseLinks(t *testing.T) {
+	content := "---\nlink: [[Front]]\n---\n" +
+		"See [[Plan#Goals|the plan]] and ![[diagram]].\r\n" +
+		"Also [notes](../notes/My%20Note.md \"title\"), [site](https://example.com), [top](#top)\n" +
+		"`[[code]]` and ![img](pic.png) [[A]][[B]]\n" +
+		"```\n[[fenced]]\n```\n"
+
+	links := parseLinks(content)
+	expected := []struct {
+		kind, target string
+		line         int
+	}{
+		{"wiki", "Plan#Goals", 4},
+		{"wiki", "diagram", 4},
+		{"markdown", "../notes/My%20Note.md", 5},
+		{"wiki", "A", 6},
+		{"wiki", "B", 6},
+	}
+	if len(links) != len(expected) {
+		t.Fatalf("expected %d links, got %+v", len(expected), links)
+	}
+	for i, link := range links {
+		if link.kind != expected[i].kind || link.target != expected[i].target || link.line != expected[i].line {
+			t.Fatalf("link %d: expected %+v, got %+v", i, expected[i], link)
+		}
+		if content[link.targetStart:link.targetEnd] != link.target {
+			t.Fatalf("link %d: the offsets don't point to the target", i)
+		}
+	}
+}
+
+func TestLinkResolver(t *testing.T) {
+	a := newTestVault(t)
+	resolver := a.newLinkResolver([]*indexedDoc{
+		{Path: "plan.md"},
+		{Path: filepath.Join("projects", "plan.md")},
+		{Path: filepath.Join("projects", "a", "deep.md")},
+		{Path: filepath.Join("projects", "a", "other.md")},
+		{Path: filepath.Join("archive", "deep.md")},
+		{Path: filepath.Join("notes", "My Note.md")},
+		{Path: "projects", IsDir: true},
+	})
+
+	source := filepath.Join("projects", "a", "deep.md")
+	tests := []struct {
+		source, target, kind, expected string
+	}{
+		{"plan.md", "Plan", "wiki", "plan.md"},
+		{source, "plan", "wiki", "plan.md"},                                    // a path from the root first
+		{filepath.Join("projects", "a", "other.md"), "deep", "wiki", source},   // then a note of the same folder
+		{"plan.md", "deep#Intro", "wiki", filepath.Join("archive", "deep.md")}, // then the shortest path
+		{"plan.md", "projects/plan", "wiki", filepath.Join("projects", "plan.md")},
+		{source, "../../notes/My%20Note.md", "markdown", filepath.Join("notes", "My Note.md")},
+		{source, "/plan.md", "markdown", "plan.md"},
+		{source, "../plan.md", "markdown", filepath.Join("projects", "plan.md")},
+		{"plan.md", "../outside.md", "markdown", ""},
+		{"plan.md", "missing", "wiki", ""},
+		{"plan.md", "projects", "wiki", ""},
+	}
+	for _, tt := range tests {
+		rel, ok := resolver.resolve(tt.source, tt.target, tt.kind)
+		if rel != tt.expected || ok != (tt.expected != "") {
+			t.Fatalf("%s => %q: expected %q, got %q", tt.source, tt.target, tt.expected, rel)
+		}
+	}
+}
+
+func TestBacklinks(t *testing.T) {
+	a := newTestVault(t)
+	os.Mkdir(filepath.Join(a.rootPath, "projects"), 0700)
+	plan := filepath.Join(a.rootPath, "projects", "plan.md")
+	home := filepath.Join(a.rootPath, "home.md")
+	a.WriteContentInFile(plan, "# Plan\n\nBack to [home](../home.md)")
+	a.WriteContentInFile(home, "Start with [[Plan]]\nand [[Missing]]")
+	a.getSearchIndex()
+
+	outgoing, err := a.GetOutgoingLinks(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outgoing) != 2 || outgoing[0].Path != plan || outgoing[0].Name != "plan.md" || outgoing[1].Resolved {
+		t.Fatalf("unexpected outgoing links %+v", outgoing)
+	}
+
+	backlinks, _ := a.GetBacklinks(plan)
+	if len(backlinks) != 1 || backlinks[0].Path != home || backlinks[0].Line != 1 || backlinks[0].Context != "Start with [[Plan]]" {
+		t.Fatalf("unexpected backlinks %+v", backlinks)
+	}
+
+	// the index follows the notes
+	a.WriteContentInFile(home, "nothing anymore")
+	if backlinks, _ := a.GetBacklinks(plan); len(backlinks) != 0 {
+		t.Fatalf("a removed link must not be a backlink anymore, got %+v", backlinks)
+	}
+	if backlinks, _ := a.GetBacklinks(home); len(backlinks) != 1 || backlinks[0].Path != plan {
+		t.Fatalf("expected the markdown link as a backlink, got %+v", backlinks)
+	}
+
+	if _, err := a.GetBacklinks(filepath.Join(a.rootPath, "..", "outside.md")); err == nil {
+		t.Fatal("expected an error for a note out of the vault")
+	}
+}
+
+func TestBacklinksEncrypted(t *testing.T) {
+	a := newTestVault(t)
+	if response := a.SetupPassword("testpassword", a.rootPath); response != "ok" {
+		t.Fatal(response)
+	}
+	folder, err := a.CreateDirectory(a.rootPath, "projects")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, _ := a.CreateFile(folder, "Plan")
+	home, _ := a.CreateFile(a.rootPath, "Home")
+	a.WriteContentInFile(home, "see [[projects/Plan]] and [plan](projects/plan.md)")
+	a.getSearchIndex()
+
+	backlinks, err := a.GetBacklinks(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backlinks) != 2 || backlinks[0].Path != home || backlinks[0].Name != "Home.mde" {
+		t.Fatalf("decrypted names must resolve to the encrypted notes, got %+v", backlinks)
+	}
+}
+
+func TestScanLinksImages(t *testing.T) {
+	refs := scanLinks("![a](img/a%20b.png) ![[b.jpg]] ![[Note]] [[c.svg]] ![x](https://x.org/y.png) [doc](file.pdf)")
+	expected := []struct {
+		target       string
+		embed, image bool
+	}{
+		{"img/a%20b.png", true, true},
+		{"b.jpg", true, true},
+		{"Note", true, false},
+		{"c.svg", false, true},
+	}
+	if len(refs) != len(expected) {
+		t.Fatalf("expected %d links, got %+v", len(expected), refs)
+	}
+	for i, ref := range refs {
+		if ref.target != expected[i].target || ref.embed != expected[i].embed || ref.image != expected[i].image {
+			t.Fatalf("link %d: expected %+v, got %+v", i, expected[i], ref)
+		}
+	}
+	if links := parseLinks("![a](a.png) ![[Note]]"); len(links) != 1 || links[0].target != "Note" {
+		t.Fatalf("images are not links to notes, got %+v", links)
+	}
+}
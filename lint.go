@@ -0,0 +1,229 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// kinds of link issues
+const (
+	lintMissingNote    = "missing_note"
+	lintMissingHeading = "missing_heading"
+	lintMissingImage   = "missing_image"
+	lintOrphan         = "orphan"
+)
+
+type LinkIssue struct {
+	Kind    string `json:"kind"`    // "missing_note", "missing_heading", "missing_image" or "orphan"
+	Path    string `json:"path"`    // the note holding the link, or the orphan note
+	Name    string `json:"name"`    // display name of that note
+	Target  string `json:"target"`  // the link target as written, empty for an orphan
+	Line    int    `json:"line"`    // 1-based, 0 for an orphan
+	Column  int    `json:"column"`  // of the target, in runes, 1-based
+	Context string `json:"context"` // the line of the link
+}
+
+type LinkLintReport struct {
+	Issues []LinkIssue `json:"issues"` // sorted by note, then position
+	Notes  int         `json:"notes"`  // notes checked
+	Links  int         `json:"links"`  // links and images checked
+}
+
+// lintNote is a note read by LintVaultLinks
+type lintNote struct {
+	doc     *indexedDoc
+	content string
+	links   []noteLinkRef
+}
+
+// vaultFiles finds the files of the vault other than notes, by display path or name
+type vaultFiles struct {
+	byPath map[string]bool // lowercased display path
+	byName map[string]bool // lowercased name
+}
+
+/**
+ * --- Link lint
+ */
+// listVaultFiles returns the files of the vault other than notes and folders, eg: images
+func (a *App) listVaultFiles(rootPath string) vaultFiles {
+	files := vaultFiles{byPath: make(map[string]bool), byName: make(map[string]bool)}
+
+	filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // skip files with errors
+		}
+		rel, err := filepath.Rel(rootPath, path)
+		if err != nil || rel == "." {
+			return nil
+		}
+		if !isIndexable(rel) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || isMDorMDE(info.Name()) {
+			return nil
+		}
+
+		shown := strings.ToLower(a.displayRelPath(rel))
+		files.byPath[shown] = true
+		files.byName[filepath.Base(shown)] = true
+		return nil
+	})
+
+	return files
+}
+
+// hasImage reports if an image linked from the note at sourceShown exists
+// markdown images are relative to the note, wiki embeds are a path from the vault root or a name
+func (files vaultFiles) hasImage(sourceShown string, ref noteLinkRef) bool {
+	file := linkTargetFile(ref.target, ref.kind)
+	if ref.kind == "wiki" {
+		key := strings.ToLower(strings.TrimPrefix(file, "/"))
+		return files.byPath[key] || (!strings.Contains(key, "/") && files.byName[key])
+	}
+
+	wanted := path.Join(path.Dir(sourceShown), file)
+	if strings.HasPrefix(file, "/") {
+		wanted = path.Clean(file[1:])
+	}
+	return !strings.HasPrefix(wanted, "../") && files.byPath[strings.ToLower(wanted)]
+}
+
+// linkFragment returns the heading a link points to, eg: "Goals" for "note#Goals"
+// empty without heading or for a block reference ("note#^id")
+func linkFragment(ref noteLinkRef) string {
+	i := strings.IndexByte(ref.target, '#')
+	if i == -1 {
+		return ""
+	}
+	fragment := ref.target[i+1:]
+	if ref.kind == "markdown" {
+		if unescaped, err := url.PathUnescape(fragment); err == nil {
+			fragment = unescaped
+		}
+	} else if j := strings.LastIndexByte(fragment, '#'); j != -1 {
+		fragment = fragment[j+1:] // [[note#Section#Subsection]]
+	}
+	if strings.HasPrefix(fragment, "^") {
+		return ""
+	}
+	return strings.TrimSpace(fragment)
+}
+
+// newLinkIssue builds an issue for a link with its position in the note
+func (a *App) newLinkIssue(kind string, root string, note *lintNote, ref noteLinkRef) LinkIssue {
+	lineStart := strings.LastIndexByte(note.content[:ref.targetStart], '\n') + 1
+	lineEnd := len(note.content)
+	if i := strings.IndexByte(note.content[ref.targetStart:], '\n'); i != -1 {
+		lineEnd = ref.targetStart + i
+	}
+	context := strings.TrimSpace(note.content[lineStart:lineEnd])
+	if len(context) > maxLinkContext {
+		context = strings.ToValidUTF8(context[:maxLinkContext], "")
+	}
+
+	return LinkIssue{
+		Kind:    kind,
+		Path:    filepath.Join(root, note.doc.Path),
+		Name:    note.doc.Name,
+		Target:  ref.target,
+		Line:    ref.line,
+		Column:  utf8.RuneCountInString(note.content[lineStart:ref.targetStart]) + 1,
+		Context: context,
+	}
+}
+
+// LintVaultLinks reports the links to missing notes, to missing headings and to missing images
+// and the notes no other note links to. Names are the decrypted ones in privacy mode
+// a locked vault is not checked
+func (a *App) LintVaultLinks(rootPath string) (LinkLintReport, error) {
+	report := LinkLintReport{Issues: []LinkIssue{}}
+	root, err := a.resolveVaultPath(rootPath)
+	if err != nil {
+		return report, err
+	}
+	if a.HasSecurity(root) && a.masterkey == nil {
+		return report, nil
+	}
+
+	docs := a.listVaultDocs(root)
+	resolver := a.newLinkResolver(docs)
+	files := a.listVaultFiles(root)
+
+	// every note is read first, for the headings the links point to
+	var notes []*lintNote
+	headings := make(map[string]map[string]bool) // rel path => heading slugs
+	for _, doc := range docs {
+		if doc.IsDir {
+			continue
+		}
+		content, err := a.ReadFile(filepath.Join(root, doc.Path))
+		if err != nil {
+			continue
+		}
+		notes = append(notes, &lintNote{doc: doc, content: content, links: scanLinks(content)})
+
+		slugs := make(map[string]bool)
+		for _, heading := range markdownHeadings(content) {
+			slugs[heading.slug] = true
+		}
+		headings[doc.Path] = slugs
+	}
+
+	linked := make(map[string]bool)
+	for _, note := range notes {
+		report.Notes++
+		for _, ref := range note.links {
+			report.Links++
+			if ref.image {
+				if !files.hasImage(resolver.shown[note.doc.Path], ref) {
+					report.Issues = append(report.Issues, a.newLinkIssue(lintMissingImage, root, note, ref))
+				}
+				continue
+			}
+
+			target, ok := resolver.resolve(note.doc.Path, ref.target, ref.kind)
+			if !ok {
+				report.Issues = append(report.Issues, a.newLinkIssue(lintMissingNote, root, note, ref))
+				continue
+			}
+			if target != note.doc.Path {
+				linked[target] = true
+			}
+			if fragment := linkFragment(ref); fragment != "" && !headings[target][headingSlug(fragment)] {
+				report.Issues = append(report.Issues, a.newLinkIssue(lintMissingHeading, root, note, ref))
+			}
+		}
+	}
+
+	for _, note := range notes {
+		if !linked[note.doc.Path] {
+			report.Issues = append(report.Issues, LinkIssue{
+				Kind: lintOrphan,
+				Path: filepath.Join(root, note.doc.Path),
+				Name: note.doc.Name,
+			})
+		}
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		x, y := report.Issues[i], report.Issues[j]
+		if x.Path != y.Path {
+			return x.Path < y.Path
+		}
+		if x.Line != y.Line {
+			return x.Line < y.Line
+		}
+		return x.Column < y.Column
+	})
+
+	return report, nil
+}
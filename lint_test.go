@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLintVaultLinks(t *testing.T) {
+	a := newTestVault(t)
+	os.Mkdir(filepath.Join(a.rootPath, "img"), 0700)
+	os.WriteFile(filepath.Join(a.rootPath, "img", "logo.png"), []byte("png"), 0600)
+	home := filepath.Join(a.rootPath, "home.md")
+	plan := filepath.Join(a.rootPath, "plan.md")
+	a.WriteContentInFile(plan, "# Goals\n\n## Next steps\n\n[[home]] [[#Goals]]")
+	a.WriteContentInFile(home, "[[plan#Goals]] [p](plan.md#next-steps) [[plan#^block]]\n"+
+		"[[missing]] [[plan#Nowhere]]\n"+
+		"![logo](img/logo.png) ![[logo.png]] ![gone](img/gone.png)")
+	a.WriteContentInFile(filepath.Join(a.rootPath, "alone.md"), "[[alone]]")
+
+	report, err := a.LintVaultLinks(a.rootPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []LinkIssue{
+		{Kind: lintOrphan, Path: filepath.Join(a.rootPath, "alone.md")},
+		{Kind: lintMissingNote, Path: home, Target: "missing", Line: 2, Column: 3},
+		{Kind: lintMissingHeading, Path: home, Target: "plan#Nowhere", Line: 2, Column: 15},
+		{Kind: lintMissingImage, Path: home, Target: "img/gone.png", Line: 3, Column: 45},
+	}
+	if len(report.Issues) != len(expected) || report.Notes != 3 || report.Links != 11 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	for i, issue := range report.Issues {
+		if issue.Kind != expected[i].Kind || issue.Path != expected[i].Path || issue.Target != expected[i].Target ||
+			issue.Line != expected[i].Line || issue.Column != expected[i].Column {
+			t.Fatalf("issue %d: expected %+v, got %+v", i, expected[i], issue)
+		}
+	}
+	if report.Issues[1].Context != "[[missing]] [[plan#Nowhere]]" || report.Issues[1].Name != "home.md" {
+		t.Fatalf("unexpected issue context %+v", report.Issues[1])
+	}
+}
+
+func TestLintVaultLinksEncrypted(t *testing.T) {
+	a := newTestVault(t)
+	if response := a.SetupPassword("testpassword", a.rootPath); response != "ok" {
+		t.Fatal(response)
+	}
+	folder, _ := a.CreateDirectory(a.rootPath, "projects")
+	plan, _ := a.CreateFile(folder, "Plan")
+	home, _ := a.CreateFile(a.rootPath, "Home")
+	a.WriteContentInFile(plan, "[[Home]]")
+	a.WriteContentInFile(home, "[[projects/Plan]] [[Plan]] [x](projects/Plan.md) [[Roadmap]]")
+
+	report, err := a.LintVaultLinks(a.rootPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Issues) != 1 || report.Issues[0].Target != "Roadmap" || report.Issues[0].Name != "Home.mde" {
+		t.Fatalf("links must be checked against the decrypted names, got %+v", report.Issues)
+	}
+
+	a.masterkey = nil
+	if report, _ := a.LintVaultLinks(a.rootPath); len(report.Issues) != 0 {
+		t.Fatal("a locked vault must not be checked")
+	}
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// markdown elements a part of a note belongs to
+const (
+	mdText        = "text"
+	mdCode        = "code"
+	mdHeading     = "heading"
+	mdLink        = "link"
+	mdFrontmatter = "frontmatter"
+)
+
+var (
+	mdHeadingPattern    = regexp.MustCompile(`^ {0,3}#{1,6}(?:[ \t]|$)`)
+	mdSetextPattern     = regexp.MustCompile(`^ {0,3}(?:=+|-+)[ \t]*$`)
+	mdBlockPattern      = regexp.MustCompile(`^ {0,3}(?:[-*+>|]|\d+[.)])(?:[ \t]|$)`) // list items, quotes and tables
+	mdInlineLinkPattern = regexp.MustCompile(`!?\[\[([^\]|]*)(?:\|([^\]]*))?\]\]|!?\[([^\]]*)\]\([^)]*\)`)
+)
+
+// mdRegion is a byte range of a note made of one kind of markdown element
+type mdRegion struct {
+	kind  string
+	start int
+	end   int
+}
+
+// markdownHeading is an ATX ("# Title") or setext (underlined) heading of a note
+type markdownHeading struct {
+	level int
+	text  string // without the inline markup
+	line  int    // 1-based
+	slug  string // unique in the note
+}
+
+// mdLine is a line of a note, end excludes the line ending and next is the start of the following line
+type mdLine struct {
+	text  string
+	start int
+	end   int
+	next  int
+}
+
+/**
+ * --- Markdown
+ */
+// isMarkdownElement reports if kind names a markdown element
+func isMarkdownElement(kind string) bool {
+	switch kind {
+	case mdText, mdCode, mdHeading, mdLink, mdFrontmatter:
+		return true
+	}
+	return false
+}
+
+// splitMarkdownLines splits content in lines with their offsets, "\r\n" endings are supported
+func splitMarkdownLines(content string) []mdLine {
+	var lines []mdLine
+	for start := 0; start < len(content); {
+		end, next := len(content), len(content)
+		if i := strings.IndexByte(content[start:], '\n'); i != -1 {
+			end, next = start+i, start+i+1
+		}
+		text := strings.TrimSuffix(content[start:end], "\r")
+		lines = append(lines, mdLine{text: text, start: start, end: start + len(text), next: next})
+		start = next
+	}
+	return lines
+}
+
+// frontmatterEnd returns the index of the line closing the front matter, -1 when the note has none
+// the front matter is a YAML block between "---" lines at the very start of the note
+func frontmatterEnd(lines []mdLine) int {
+	if len(lines) == 0 || lines[0].text != "---" {
+		return -1
+	}
+	for i := 1; i < len(lines); i++ {
+		if lines[i].text == "---" || lines[i].text == "..." {
+			return i
+		}
+	}
+	return -1
+}
+
+// codeFence returns the fence character and length when the line opens or closes a fenced code block
+func codeFence(line string) (byte, int) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || len(trimmed) < 3 || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return 0, 0
+	}
+	length := len(trimmed) - len(strings.TrimLeft(trimmed, trimmed[:1]))
+	if length < 3 {
+		return 0, 0
+	}
+	return trimmed[0], length
+}
+
+// closesFence reports if a line closes the fenced code block opened with fenceLength fenceChar
+// a closing fence has no info string and is at least as long as the opening one
+func closesFence(line string, fenceChar byte, fenceLength int) bool {
+	char, length := codeFence(line)
+	trimmed := strings.TrimSpace(line)
+	return char == fenceChar && length >= fenceLength && trimmed == strings.Repeat(string(char), len(trimmed))
+}
+
+// markdownRegions splits a note in front matter, fenced code blocks, headings, inline code, links and text
+// it is a light line based parse, enough to know what a part of the note is, not to render it
+func markdownRegions(content string) []mdRegion {
+	var regions []mdRegion
+	add := func(kind string, start, end int) {
+		if start >= end {
+			return
+		}
+		if last := len(regions) - 1; last >= 0 && regions[last].kind == kind && regions[last].end == start {
+			regions[last].end = end
+			return
+		}
+		regions = append(regions, mdRegion{kind, start, end})
+	}
+
+	lines := splitMarkdownLines(content)
+	first := 0
+	if end := frontmatterEnd(lines); end != -1 {
+		add(mdFrontmatter, 0, lines[end].next)
+		first = end + 1
+	}
+
+	// rewind drops the regions from start, once a setext underline turns the paragraph above into a heading
+	rewind := func(start int) {
+		for len(regions) > 0 && regions[len(regions)-1].start >= start {
+			regions = regions[:len(regions)-1]
+		}
+		if last := len(regions) - 1; last >= 0 && regions[last].end > start {
+			regions[last].end = start
+		}
+	}
+
+	var fenceChar byte
+	fenceLength := 0
+	paragraph := -1 // start of the paragraph a setext underline turns into a heading, as in markdownHeadings
+	for _, line := range lines[first:] {
+		char, length := codeFence(line.text)
+		switch {
+		case fenceLength > 0:
+			add(mdCode, line.start, line.next)
+			if closesFence(line.text, fenceChar, fenceLength) {
+				fenceLength = 0
+			}
+		case length > 0:
+			add(mdCode, line.start, line.next)
+			fenceChar, fenceLength = char, length
+			paragraph = -1
+		case mdHeadingPattern.MatchString(line.text):
+			add(mdHeading, line.start, line.next)
+			paragraph = -1
+		case paragraph != -1 && mdSetextPattern.MatchString(line.text):
+			rewind(paragraph)
+			add(mdHeading, paragraph, line.next)
+			paragraph = -1
+		default:
+			inlineRegions(content, line.start, line.next, add)
+			if strings.TrimSpace(line.text) == "" || mdBlockPattern.MatchString(line.text) || mdSetextPattern.MatchString(line.text) {
+				paragraph = -1
+			} else if paragraph == -1 {
+				paragraph = line.start
+			}
+		}
+	}
+
+	return regions
+}
+
+// inlineRegions splits a line of text in inline code spans, links and text
+func inlineRegions(content string, start, end int, add func(kind string, start, end int)) {
+	textStart := start
+	for i := start; i < end; {
+		regionStart, regionEnd, kind := i, -1, ""
+
+		switch content[i] {
+		case '`':
+			run := backtickRun(content, i, end)
+			// the span ends with a run of the same length
+			for j := i + run; j < end; j++ {
+				if content[j] != '`' {
+					continue
+				}
+				closing := backtickRun(content, j, end)
+				if closing == run {
+					regionEnd, kind = j+closing, mdCode
+					break
+				}
+				j += closing - 1
+			}
+			if regionEnd == -1 {
+				i += run
+				continue
+			}
+		case '[':
+			if i > textStart && content[i-1] == '!' {
+				regionStart = i - 1 // an image
+			}
+			regionEnd = linkEnd(content, i, end)
+			kind = mdLink
+		case '<':
+			if close := strings.IndexByte(content[i:end], '>'); close != -1 {
+				target := content[i+1 : i+close]
+				if !strings.ContainsAny(target, " \t") && (strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:")) {
+					regionEnd, kind = i+close+1, mdLink
+				}
+			}
+		}
+
+		if regionEnd == -1 {
+			i++
+			continue
+		}
+		add(mdText, textStart, regionStart)
+		add(kind, regionStart, regionEnd)
+		i, textStart = regionEnd, regionEnd
+	}
+	add(mdText, textStart, end)
+}
+
+// backtickRun returns the number of backticks starting at i
+func backtickRun(content string, i, end int) int {
+	run := 0
+	for i+run < end && content[i+run] == '`' {
+		run++
+	}
+	return run
+}
+
+// linkEnd returns the end of the [[wiki link]] or [text](target) starting at i, -1 when there is none
+func linkEnd(content string, i, end int) int {
+	if strings.HasPrefix(content[i:end], "[[") {
+		if close := strings.Index(content[i+2:end], "]]"); close != -1 {
+			return i + 2 + close + 2
+		}
+		return -1
+	}
+
+	close := strings.IndexByte(content[i+1:end], ']')
+	if close == -1 {
+		return -1
+	}
+	open := i + 1 + close + 1
+	if open >= end || content[open] != '(' {
+		return -1
+	}
+	if target := strings.IndexByte(content[open:end], ')'); target != -1 {
+		return open + target + 1
+	}
+	return -1
+}
+
+// regionAt returns the kind of markdown element at offset
+func regionAt(regions []mdRegion, offset int) string {
+	i := sort.Search(len(regions), func(i int) bool {
+		return regions[i].end > offset
+	})
+	if i < len(regions) && regions[i].start <= offset {
+		return regions[i].kind
+	}
+	return mdText
+}
+
+// markdownHeadings returns the headings of a note, the ones in code blocks or in the front matter are ignored
+func markdownHeadings(content string) []markdownHeading {
+	var headings []markdownHeading
+	slugs := make(map[string]bool)
+	add := func(level int, text string, line int) {
+		text = headingText(text)
+		headings = append(headings, markdownHeading{level: level, text: text, line: line, slug: uniqueSlug(slugs, headingSlug(text))})
+	}
+
+	lines := splitMarkdownLines(content)
+	first := 0
+	if end := frontmatterEnd(lines); end != -1 {
+		first = end + 1
+	}
+
+	var fenceChar byte
+	fenceLength := 0
+	paragraph := -1 // first line of the paragraph a setext underline turns into a heading
+	for i := first; i < len(lines); i++ {
+		text := lines[i].text
+		if fenceLength > 0 {
+			if closesFence(text, fenceChar, fenceLength) {
+				fenceLength = 0
+			}
+			continue
+		}
+
+		char, length := codeFence(text)
+		switch {
+		case length > 0:
+			fenceChar, fenceLength = char, length
+			paragraph = -1
+		case mdHeadingPattern.MatchString(text):
+			trimmed := strings.TrimLeft(text, " ")
+			level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			add(level, trimATXClosing(trimmed[level:]), i+1)
+			paragraph = -1
+		case paragraph != -1 && mdSetextPattern.MatchString(text):
+			level := 2
+			if strings.TrimSpace(text)[0] == '=' {
+				level = 1
+			}
+			var parts []string
+			for _, line := range lines[paragraph:i] {
+				parts = append(parts, strings.TrimSpace(line.text))
+			}
+			add(level, strings.Join(parts, " "), paragraph+1)
+			paragraph = -1
+		case strings.TrimSpace(text) == "" || mdBlockPattern.MatchString(text) || mdSetextPattern.MatchString(text):
+			paragraph = -1
+		case paragraph == -1:
+			paragraph = i
+		}
+	}
+
+	return headings
+}
+
+// trimATXClosing strips the optional closing sequence of an ATX heading, eg: "Title ##"
+func trimATXClosing(text string) string {
+	text = strings.TrimSpace(text)
+	closing := strings.TrimRight(text, "#")
+	if closing == "" || strings.HasSuffix(closing, " ") || strings.HasSuffix(closing, "\t") {
+		return strings.TrimSpace(closing)
+	}
+	return text
+}
+
+// headingText returns a heading without its inline markup: links are replaced by their text
+func headingText(text string) string {
+	text = mdInlineLinkPattern.ReplaceAllStringFunc(text, func(link string) string {
+		parts := mdInlineLinkPattern.FindStringSubmatch(link)
+		for _, part := range []string{parts[2], parts[1], parts[3]} {
+			if part != "" {
+				return part
+			}
+		}
+		return ""
+	})
+	text = strings.NewReplacer("**", "", "__", "", "~~", "", "`", "", "*", "").Replace(text)
+	return strings.TrimSpace(text)
+}
+
+// headingSlug returns the anchor of a heading the way GitHub makes it: lowercased, punctuation dropped
+// and spaces replaced by "-". The reader gives its headings the same ids, see rehypeHeadingIds.ts
+func headingSlug(text string) string {
+	var slug strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case r == ' ':
+			slug.WriteByte('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			slug.WriteRune(r)
+		}
+	}
+	return slug.String()
+}
+
+// uniqueSlug suffixes a slug already used in the note with "-1", "-2"...
+func uniqueSlug(used map[string]bool, slug string) string {
+	unique := slug
+	for i := 1; used[unique]; i++ {
+		unique = slug + "-" + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
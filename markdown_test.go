@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownRegions(t *testing.T) {
+	content := "---\ntags: [a]\n---\n# Title\nSome `code` and [a link](b.md), [[Wiki]] ![img](i.png) <https://x.org>\n```bash\nls -la\n```\nafter ``a ` b`` end\n"
+
+	expected := map[string]string{
+		"tags: [a]":       mdFrontmatter,
+		"Title":           mdHeading,
+		"Some":            mdText,
+		"`code`":          mdCode,
+		"[a link](b.md)":  mdLink,
+		"[[Wiki]]":        mdLink,
+		"![img](i.png)":   mdLink,
+		"<https://x.org>": mdLink,
+		"bash":            mdCode,
+		"ls -la":          mdCode,
+		"after":           mdText,
+		"``a ` b``":       mdCode,
+		" end":            mdText,
+	}
+
+	regions := markdownRegions(content)
+	for part, kind := range expected {
+		offset := strings.Index(content, part)
+		if got := regionAt(regions, offset); got != kind {
+			t.Fatalf("%q: expected %s, got %s", part, kind, got)
+		}
+		if got := regionAt(regions, offset+len(part)-1); got != kind {
+			t.Fatalf("%q: expected %s up to its end, got %s", part, kind, got)
+		}
+	}
+}
+
+func TestMarkdownRegionsUnclosed(t *testing.T) {
+	// an unclosed front matter is text, an unclosed fence runs to the end of the note
+	regions := markdownRegions("---\nnot front matter\n~~~~\ncode\n~~~\nstill code\n")
+	if regionAt(regions, 5) != mdText {
+		t.Fatal("an unclosed front matter must be text")
+	}
+	if regionAt(regions, len("---\nnot front matter\n~~~~\ncode\n~~~\nstill")) != mdCode {
+		t.Fatal("a shorter fence must not close the code block")
+	}
+
+	regions = markdownRegions("[not a link] and `not code\n#not a heading")
+	for _, region := range regions {
+		if region.kind != mdText {
+			t.Fatalf("unexpected %s region", region.kind)
+		}
+	}
+}
+
+func TestMarkdownRegionsSetext(t *testing.T) {
+	content := "intro\n\nSetext [[link]] and `code`\non two lines\n===\nafter\n- item\n---\ntext\n"
+	regions := markdownRegions(content)
+
+	start := strings.Index(content, "Setext")
+	end := strings.Index(content, "after")
+	for offset := start; offset < end; offset++ {
+		if kind := regionAt(regions, offset); kind != mdHeading {
+			t.Fatalf("%q: expected heading, got %s", content[offset:end], kind)
+		}
+	}
+	if regionAt(regions, 0) != mdText || regionAt(regions, end) != mdText {
+		t.Fatal("the lines around a setext heading must stay text")
+	}
+	if regionAt(regions, strings.Index(content, "item")) != mdText || regionAt(regions, strings.Index(content, "---")) != mdText {
+		t.Fatal("a line under a list item doesn't underline a heading")
+	}
+}
+
+func TestHeadingSlug(t *testing.T) {
+	// the reader makes the same ids, keep frontend/src/services/rehypeHeadingIds.ts in line
+	tests := map[string]string{
+		"Intro":                       "intro",
+		"Été 2026!":                   "été-2026",
+		"[[Goals]] a_b":               "goals-a_b",
+		"[[Plan|The plan]] & `code`":  "the-plan--code",
+		"  **Bold** and [link](x.md)": "bold-and-link",
+	}
+	for text, expected := range tests {
+		if slug := headingSlug(headingText(text)); slug != expected {
+			t.Fatalf("%q: expected %q, got %q", text, expected, slug)
+		}
+	}
+}
+
+func TestMarkdownHeadings(t *testing.T) {
+	content := "---\ntitle: x\n---\n" +
+		"# Intro ##\n" +
+		"Setext *title*\non two lines\n===\n\n" +
+		"```\n# not a heading\n```\n" +
+		"- item\n---\n" +
+		"## [[Plan|The plan]] & `code`\n" +
+		"# Intro\n" +
+		"### Été 2026!\n"
+
+	expected := []markdownHeading{
+		{1, "Intro", 4, "intro"},
+		{1, "Setext title on two lines", 5, "setext-title-on-two-lines"},
+		{2, "The plan & code", 14, "the-plan--code"},
+		{1, "Intro", 15, "intro-1"},
+		{3, "Été 2026!", 16, "été-2026"},
+	}
+	headings := markdownHeadings(content)
+	if len(headings) != len(expected) {
+		t.Fatalf("expected %d headings, got %+v", len(expected), headings)
+	}
+	for i, heading := range headings {
+		if heading != expected[i] {
+			t.Fatalf("heading %d: expected %+v, got %+v", i, expected[i], heading)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	mergeMarkerOurs   = "<<<<<<< ours\n"
+	mergeMarkerSep    = "=======\n"
+	mergeMarkerTheirs = ">>>>>>> theirs\n"
+)
+
+type MergeConflict struct {
+	StartLine int    `json:"startLine"` // line of the opening marker in the merged text, 0-based
+	EndLine   int    `json:"endLine"`   // line of the closing marker in the merged text, 0-based
+	Base      string `json:"base"`
+	Ours      string `json:"ours"`
+	Theirs    string `json:"theirs"`
+	Diff      Diff   `json:"diff"` // from ours to theirs
+}
+
+type MergeResult struct {
+	Merged    string          `json:"merged"` // conflicts are written with git style markers
+	Clean     bool            `json:"clean"`
+	Conflicts []MergeConflict `json:"conflicts"`
+}
+
+// lineHunk replaces the base lines [start, end) with lines
+type lineHunk struct {
+	start int
+	end   int
+	lines []string
+}
+
+/**
+ * --- Merge
+ */
+// splitLines splits a text in lines keeping the line endings, like diffmatchpatch line mode does
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLineHunks returns the line hunks turning base into other, ordered by position in base
+func diffLineHunks(base, other string) []lineHunk {
+	dmp := diffmatchpatch.New()
+	runesBase, runesOther, lineArray := dmp.DiffLinesToRunes(base, other)
+	diffs := dmp.DiffMainRunes(runesBase, runesOther, false)
+	diffs = dmp.DiffCharsToLines(diffs, lineArray)
+
+	var hunks []lineHunk
+	baseIdx := 0
+	inChange := false // consecutive deletes and inserts make a single hunk
+
+	for _, diff := range diffs {
+		lines := splitLines(diff.Text)
+		if diff.Text == "" {
+			continue
+		}
+
+		switch diff.Type {
+		case diffmatchpatch.DiffEqual:
+			baseIdx += len(lines)
+			inChange = false
+		case diffmatchpatch.DiffDelete:
+			if !inChange {
+				hunks = append(hunks, lineHunk{start: baseIdx, end: baseIdx})
+				inChange = true
+			}
+			baseIdx += len(lines)
+			hunks[len(hunks)-1].end = baseIdx
+		case diffmatchpatch.DiffInsert:
+			if !inChange {
+				hunks = append(hunks, lineHunk{start: baseIdx, end: baseIdx})
+				inChange = true
+			}
+			hunks[len(hunks)-1].lines = append(hunks[len(hunks)-1].lines, lines...)
+		}
+	}
+
+	return hunks
+}
+
+// applyLineHunks returns the base lines [lo, hi) with the hunks applied, hunks must lie in that range
+func applyLineHunks(baseLines []string, lo, hi int, hunks []lineHunk) []string {
+	var result []string
+	pos := lo
+	for _, hunk := range hunks {
+		result = append(result, baseLines[pos:hunk.start]...)
+		result = append(result, hunk.lines...)
+		pos = hunk.end
+	}
+	return append(result, baseLines[pos:hi]...)
+}
+
+// mergeLines merges ours and theirs changes made on base
+// changes touching the same or adjacent base lines on both sides are conflicts unless they are identical
+// with union set, conflicts are resolved by keeping ours then theirs lines
+func mergeLines(base, ours, theirs string, union bool) MergeResult {
+	baseLines := splitLines(base)
+	oursHunks := diffLineHunks(base, ours)
+	theirsHunks := diffLineHunks(base, theirs)
+
+	var merged []string
+	conflicts := []MergeConflict{}
+	pos := 0
+	i, j := 0, 0
+
+	for i < len(oursHunks) || j < len(theirsHunks) {
+		// the group starts with the first hunk in base order then absorbs every hunk of both sides touching it
+		var groupOurs, groupTheirs []lineHunk
+		lo, hi := 0, 0
+		if j >= len(theirsHunks) || (i < len(oursHunks) && oursHunks[i].start <= theirsHunks[j].start) {
+			lo, hi = oursHunks[i].start, oursHunks[i].end
+			groupOurs = append(groupOurs, oursHunks[i])
+			i++
+		} else {
+			lo, hi = theirsHunks[j].start, theirsHunks[j].end
+			groupTheirs = append(groupTheirs, theirsHunks[j])
+			j++
+		}
+		for {
+			if i < len(oursHunks) && oursHunks[i].start <= hi {
+				hi = max(hi, oursHunks[i].end)
+				groupOurs = append(groupOurs, oursHunks[i])
+				i++
+			} else if j < len(theirsHunks) && theirsHunks[j].start <= hi {
+				hi = max(hi, theirsHunks[j].end)
+				groupTheirs = append(groupTheirs, theirsHunks[j])
+				j++
+			} else {
+				break
+			}
+		}
+
+		merged = append(merged, baseLines[pos:lo]...)
+		pos = hi
+
+		oursLines := applyLineHunks(baseLines, lo, hi, groupOurs)
+		theirsLines := applyLineHunks(baseLines, lo, hi, groupTheirs)
+		oursText := strings.Join(oursLines, "")
+		theirsText := strings.Join(theirsLines, "")
+
+		if len(groupTheirs) == 0 || oursText == theirsText {
+			merged = append(merged, oursLines...)
+			continue
+		}
+		if len(groupOurs) == 0 {
+			merged = append(merged, theirsLines...)
+			continue
+		}
+
+		if union {
+			merged = append(merged, withTrailingNewline(oursLines)...)
+			merged = append(merged, theirsLines...)
+			continue
+		}
+
+		// the conflicting block must start on its own line
+		if len(merged) > 0 && !strings.HasSuffix(merged[len(merged)-1], "\n") {
+			merged[len(merged)-1] += "\n"
+		}
+		conflict := MergeConflict{
+			StartLine: len(merged),
+			Base:      strings.Join(baseLines[lo:hi], ""),
+			Ours:      oursText,
+			Theirs:    theirsText,
+		}
+		merged = append(merged, mergeMarkerOurs)
+		merged = append(merged, withTrailingNewline(oursLines)...)
+		merged = append(merged, mergeMarkerSep)
+		merged = append(merged, withTrailingNewline(theirsLines)...)
+		conflict.EndLine = len(merged)
+		merged = append(merged, mergeMarkerTheirs)
+		conflicts = append(conflicts, conflict)
+	}
+
+	merged = append(merged, baseLines[pos:]...)
+
+	return MergeResult{
+		Merged:    strings.Join(merged, ""),
+		Clean:     len(conflicts) == 0,
+		Conflicts: conflicts,
+	}
+}
+
+// withTrailingNewline makes sure the last line ends with a newline so a marker can follow it
+func withTrailingNewline(lines []string) []string {
+	if len(lines) == 0 || strings.HasSuffix(lines[len(lines)-1], "\n") {
+		return lines
+	}
+	lines = append([]string{}, lines...)
+	lines[len(lines)-1] += "\n"
+	return lines
+}
+
+// MergeContents performs a three-way merge of ours and theirs, both derived from base
+func (a *App) MergeContents(base, ours, theirs string) MergeResult {
+	switch {
+	case ours == theirs || base == theirs:
+		return MergeResult{Merged: ours, Clean: true, Conflicts: []MergeConflict{}}
+	case base == ours:
+		return MergeResult{Merged: theirs, Clean: true, Conflicts: []MergeConflict{}}
+	}
+
+	result := mergeLines(base, ours, theirs, false)
+	for i, conflict := range result.Conflicts {
+		result.Conflicts[i].Diff = a.GetContentDiff(conflict.Ours, conflict.Theirs)
+	}
+	return result
+}
+
+// MergeWithFile merges the edited content of a note with the content changed on disk since base was read
+func (a *App) MergeWithFile(filePath, base, ours string) (MergeResult, error) {
+	theirs, err := a.ReadFile(filePath)
+	if err != nil {
+		return MergeResult{}, err
+	}
+	return a.MergeContents(base, ours, theirs), nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeContentsClean(t *testing.T) {
+	a := newTestApp("testpassword")
+	base := "title\n\nfirst\nsecond\nthird\n"
+	ours := "title\n\nfirst edited\nsecond\nthird\n"
+	theirs := "title\n\nfirst\nsecond\nthird\nfourth\n"
+
+	result := a.MergeContents(base, ours, theirs)
+
+	if !result.Clean {
+		t.Fatalf("expected a clean merge, got conflicts %+v", result.Conflicts)
+	}
+	expected := "title\n\nfirst edited\nsecond\nthird\nfourth\n"
+	if result.Merged != expected {
+		t.Fatalf("expected %q, got %q", expected, result.Merged)
+	}
+}
+
+func TestMergeContentsIdenticalChanges(t *testing.T) {
+	a := newTestApp("testpassword")
+	base := "a\nb\nc\n"
+	ours := "a\nB\nc\nd\n"
+	theirs := "a\nB\nc\n"
+
+	result := a.MergeContents(base, ours, theirs)
+
+	if !result.Clean || result.Merged != ours {
+		t.Fatalf("expected both identical edits to merge cleanly, got %q", result.Merged)
+	}
+}
+
+func TestMergeContentsConflict(t *testing.T) {
+	a := newTestApp("testpassword")
+	base := "a\nb\nc"
+	ours := "a\nours\nc"
+	theirs := "a\ntheirs\nc"
+
+	result := a.MergeContents(base, ours, theirs)
+
+	if result.Clean || len(result.Conflicts) != 1 {
+		t.Fatalf("expected one conflict, got %+v", result)
+	}
+	expected := "a\n<<<<<<< ours\nours\n=======\ntheirs\n>>>>>>> theirs\nc"
+	if result.Merged != expected {
+		t.Fatalf("expected %q, got %q", expected, result.Merged)
+	}
+
+	conflict := result.Conflicts[0]
+	if conflict.Base != "b\n" || conflict.Ours != "ours\n" || conflict.Theirs != "theirs\n" {
+		t.Fatalf("unexpected conflict sides %+v", conflict)
+	}
+	if conflict.StartLine != 1 || conflict.EndLine != 5 {
+		t.Fatalf("unexpected conflict lines %d-%d", conflict.StartLine, conflict.EndLine)
+	}
+	if conflict.Diff.DiffString == "" {
+		t.Fatal("expected a diff between both sides")
+	}
+}
+
+func TestMergeContentsConflictWithoutTrailingNewline(t *testing.T) {
+	a := newTestApp("testpassword")
+
+	result := a.MergeContents("a\nb", "a\nours", "a\ntheirs")
+
+	expected := "a\n<<<<<<< ours\nours\n=======\ntheirs\n>>>>>>> theirs\n"
+	if result.Merged != expected {
+		t.Fatalf("expected %q, got %q", expected, result.Merged)
+	}
+}
+
+func TestMergeContentsBothInsertAtEnd(t *testing.T) {
+	a := newTestApp("testpassword")
+
+	result := a.MergeContents("a\n", "a\nours\n", "a\ntheirs\n")
+
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Base != "" {
+		t.Fatalf("expected an insertion conflict, got %+v", result)
+	}
+}
+
+func TestMergeWithFile(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+	base := "one\ntwo\nthree\n"
+	a.WriteContentInFile(note, "one\ntwo\nthree changed outside\n")
+
+	result, err := a.MergeWithFile(note, base, "one edited\ntwo\nthree\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Clean || result.Merged != "one edited\ntwo\nthree changed outside\n" {
+		t.Fatalf("unexpected merge %+v", result)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// markers of the table of contents written by InsertTOC
+const (
+	tocStart = "<!-- toc -->"
+	tocEnd   = "<!-- /toc -->"
+)
+
+type OutlineHeading struct {
+	Level    int               `json:"level"`  // 1 to 6
+	Text     string            `json:"text"`   // without markdown, eg: "Goals" for "## **Goals** ##"
+	Line     int               `json:"line"`   // 1-based
+	Anchor   string            `json:"anchor"` // id of the heading in the reader, eg: "goals-1" for the second "Goals"
+	Children []*OutlineHeading `json:"children,omitempty"`
+}
+
+/**
+ * --- Outline
+ */
+// outlineTree nests headings under the previous heading of a lower level
+func outlineTree(headings []markdownHeading) []*OutlineHeading {
+	roots := []*OutlineHeading{}
+	var parents []*OutlineHeading
+	for _, heading := range headings {
+		node := &OutlineHeading{Level: heading.level, Text: heading.text, Line: heading.line, Anchor: heading.slug}
+		for len(parents) > 0 && parents[len(parents)-1].Level >= node.Level {
+			parents = parents[:len(parents)-1]
+		}
+		if len(parents) == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := parents[len(parents)-1]
+			parent.Children = append(parent.Children, node)
+		}
+		parents = append(parents, node)
+	}
+	return roots
+}
+
+// GetOutline returns the ATX and setext headings of a note as a tree, the ones in code blocks are left out
+func (a *App) GetOutline(path string) ([]*OutlineHeading, error) {
+	content, err := a.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return outlineTree(markdownHeadings(content)), nil
+}
+
+// tocLines returns the lines of the table of contents of a note, between its markers, -1 when it has none
+func tocLines(content string, lines []mdLine) (int, int, error) {
+	regions := markdownRegions(content)
+	start := -1
+	for i, line := range lines {
+		if regionAt(regions, line.start) == mdCode || regionAt(regions, line.start) == mdFrontmatter {
+			continue
+		}
+		switch strings.TrimSpace(line.text) {
+		case tocStart:
+			if start != -1 {
+				return 0, 0, fmt.Errorf("invalid_toc")
+			}
+			start = i
+		case tocEnd:
+			if start == -1 {
+				return 0, 0, fmt.Errorf("invalid_toc")
+			}
+			return start, i, nil
+		}
+	}
+	if start != -1 {
+		return 0, 0, fmt.Errorf("invalid_toc") // not closed
+	}
+	return -1, -1, nil
+}
+
+// writeTOC writes the items of a table of contents, nested by two spaces
+func writeTOC(out *strings.Builder, headings []*OutlineHeading, depth int, newline string) {
+	for _, heading := range headings {
+		fmt.Fprintf(out, "%s- [%s](#%s)%s", strings.Repeat("  ", depth), escapeLinkText(heading.Text), heading.Anchor, newline)
+		writeTOC(out, heading.Children, depth+1, newline)
+	}
+}
+
+// escapeLinkText escapes the brackets of the text of a markdown link
+func escapeLinkText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(text)
+}
+
+// tableOfContents returns the content of a note with its table of contents written or updated
+// a new one goes after the title of the note, or at its start, and lists the headings after it
+func tableOfContents(content string) (string, error) {
+	lines := splitMarkdownLines(content)
+	start, end, err := tocLines(content, lines)
+	if err != nil {
+		return "", err
+	}
+	newline := "\n"
+	if len(lines) > 0 && strings.HasSuffix(content[:lines[0].next], "\r\n") {
+		newline = "\r\n"
+	}
+
+	var before, after string
+	from := 0 // index of the first line whose headings are listed
+	if start != -1 {
+		before, after = content[:lines[start].start], content[lines[end].next:]
+		from = end + 1
+	} else {
+		// after the front matter, and after the title when the note starts with it
+		at := 0
+		if fm := frontmatterEnd(lines); fm != -1 {
+			at = fm + 1
+		}
+		for at < len(lines) && strings.TrimSpace(lines[at].text) == "" {
+			at++
+		}
+		if at < len(lines) && mdHeadingPattern.MatchString(lines[at].text) && !strings.HasPrefix(strings.TrimLeft(lines[at].text, " "), "##") {
+			before = content[:lines[at].end] + newline + newline
+			at++
+			for at < len(lines) && strings.TrimSpace(lines[at].text) == "" {
+				at++
+			}
+		} else if at < len(lines) {
+			before = content[:lines[at].start]
+		} else {
+			before = content
+			if before != "" && !strings.HasSuffix(before, "\n") {
+				before += newline
+			}
+		}
+		if at < len(lines) {
+			after = newline + content[lines[at].start:]
+		}
+		from = at
+	}
+
+	var listed []markdownHeading
+	for _, heading := range markdownHeadings(content) {
+		if heading.line > from {
+			listed = append(listed, heading)
+		}
+	}
+
+	var toc strings.Builder
+	toc.WriteString(tocStart + newline)
+	writeTOC(&toc, outlineTree(listed), 0, newline)
+	toc.WriteString(tocEnd + newline)
+
+	return before + toc.String() + after, nil
+}
+
+// InsertTOC writes a table of contents of the headings of a note between "<!-- toc -->" and "<!-- /toc -->"
+// the one already written is updated, the note is written through WriteContentInFile
+func (a *App) InsertTOC(path string) error {
+	content, err := a.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	updated, err := tableOfContents(content)
+	if err != nil || updated == content {
+		return err
+	}
+	return a.WriteContentInFile(path, updated)
+}
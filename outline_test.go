@@ -0,0 +1,87 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGetOutline(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "a.md")
+	a.WriteContentInFile(note, "# Title\n## Goals\n### Soon\n```\n# fenced\n```\nDone\n----\n#### Deep\n## Goals\n# Other\n")
+
+	outline, err := a.GetOutline(note)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outline) != 2 || outline[0].Text != "Title" || outline[1].Anchor != "other" || outline[1].Line != 11 {
+		t.Fatalf("unexpected outline %+v", outline)
+	}
+	goals := outline[0].Children
+	if len(goals) != 3 || goals[0].Anchor != "goals" || goals[2].Anchor != "goals-1" {
+		t.Fatalf("unexpected headings %+v", goals)
+	}
+	if goals[1].Text != "Done" || goals[1].Level != 2 || goals[1].Line != 7 {
+		t.Fatalf("expected a setext heading, got %+v", goals[1])
+	}
+	if len(goals[0].Children) != 1 || goals[0].Children[0].Text != "Soon" {
+		t.Fatalf("unexpected subheadings %+v", goals[0].Children)
+	}
+	if len(goals[1].Children) != 1 || goals[1].Children[0].Level != 4 {
+		t.Fatalf("a skipped level must nest under the previous heading, got %+v", goals[1].Children)
+	}
+}
+
+func TestTableOfContents(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{
+			"---\ntitle: a\n---\n# Title\n\nIntro\n## A [b]\n### C\n",
+			"---\ntitle: a\n---\n# Title\n\n<!-- toc -->\n- [A \\[b\\]](#a-b)\n  - [C](#c)\n<!-- /toc -->\n\nIntro\n## A [b]\n### C\n",
+		},
+		{
+			"Intro\r\n## A\r\n",
+			"<!-- toc -->\r\n- [A](#a)\r\n<!-- /toc -->\r\n\r\nIntro\r\n## A\r\n",
+		},
+		{
+			"# A\n<!-- toc -->\n- [Old](#old)\n<!-- /toc -->\n## B\n```\n<!-- toc -->\n```\n",
+			"# A\n<!-- toc -->\n- [B](#b)\n<!-- /toc -->\n## B\n```\n<!-- toc -->\n```\n",
+		},
+		{"# Title", "# Title\n\n<!-- toc -->\n<!-- /toc -->\n"},
+		{"", "<!-- toc -->\n<!-- /toc -->\n"},
+	}
+
+	for _, tt := range tests {
+		content, err := tableOfContents(tt.content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if content != tt.expected {
+			t.Fatalf("%q: expected %q, got %q", tt.content, tt.expected, content)
+		}
+		again, _ := tableOfContents(content)
+		if again != content {
+			t.Fatalf("%q: an updated table of contents must not change, got %q", tt.content, again)
+		}
+	}
+
+	if _, err := tableOfContents("<!-- toc -->\n## A\n"); err == nil || err.Error() != "invalid_toc" {
+		t.Fatalf("expected invalid_toc, got %v", err)
+	}
+}
+
+func TestInsertTOC(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "a.md")
+	a.WriteContentInFile(note, "# Title\n## A\n")
+
+	if err := a.InsertTOC(note); err != nil {
+		t.Fatal(err)
+	}
+	expected := "# Title\n\n<!-- toc -->\n- [A](#a)\n<!-- /toc -->\n\n## A\n"
+	if content, _ := a.ReadFile(note); content != expected {
+		t.Fatalf("expected %q, got %q", expected, content)
+	}
+}
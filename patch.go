@@ -0,0 +1,360 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	patchContextLines = 3
+	patchNoNewline    = "\\ No newline at end of file"
+)
+
+var patchHunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+type PatchHunkReject struct {
+	Index  int    `json:"index"`  // position of the hunk in the patch, 0-based
+	Header string `json:"header"` // the @@ line
+	Text   string `json:"text"`   // the hunk body as written in the patch
+}
+
+type PatchResult struct {
+	Applied  bool              `json:"applied"` // the note was written, only when no hunk got rejected
+	Hunks    int               `json:"hunks"`
+	Rejected []PatchHunkReject `json:"rejected"`
+	Diff     Diff              `json:"diff"` // changes the patch makes (or would make) to the note
+}
+
+// patchLine is one line of a unified diff: ' ' context, '-' removed or '+' added
+type patchLine struct {
+	kind byte
+	text string // with its line ending, if any
+}
+
+type patchHunk struct {
+	oldStart int
+	oldCount int
+	newStart int
+	newCount int
+	header   string
+	body     string
+	lines    []patchLine
+}
+
+/**
+ * --- Patch
+ */
+// toPatchLines turns a line-mode diff into unified diff lines
+func toPatchLines(diffs []diffmatchpatch.Diff) []patchLine {
+	var lines []patchLine
+	for _, diff := range diffs {
+		kind := byte(' ')
+		switch diff.Type {
+		case diffmatchpatch.DiffDelete:
+			kind = '-'
+		case diffmatchpatch.DiffInsert:
+			kind = '+'
+		}
+		for _, line := range splitLines(diff.Text) {
+			lines = append(lines, patchLine{kind, line})
+		}
+	}
+	return lines
+}
+
+// formatHunkRange formats a hunk range like GNU diff: the count is omitted when 1
+// and an empty range points to the line before
+func formatHunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return strconv.Itoa(start) + ",0"
+	case 1:
+		return strconv.Itoa(start + 1)
+	default:
+		return strconv.Itoa(start+1) + "," + strconv.Itoa(count)
+	}
+}
+
+// unifiedDiff returns the unified diff turning original into current, without file headers
+func unifiedDiff(original, current string) string {
+	lines := toPatchLines(diffLines(original, current))
+
+	// line numbers (0-based) in both texts before each line
+	oldAt := make([]int, len(lines)+1)
+	newAt := make([]int, len(lines)+1)
+	for i, line := range lines {
+		oldAt[i+1], newAt[i+1] = oldAt[i], newAt[i]
+		if line.kind != '+' {
+			oldAt[i+1]++
+		}
+		if line.kind != '-' {
+			newAt[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	i := 0
+	for i < len(lines) {
+		if lines[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		start := max(i-patchContextLines, 0)
+		// extend the hunk while the next change is close enough to share context
+		lastChange := i
+		for j := i + 1; j < len(lines) && j-lastChange <= 2*patchContextLines; j++ {
+			if lines[j].kind != ' ' {
+				lastChange = j
+			}
+		}
+		end := min(lastChange+patchContextLines+1, len(lines))
+
+		out.WriteString("@@ -" + formatHunkRange(oldAt[start], oldAt[end]-oldAt[start]))
+		out.WriteString(" +" + formatHunkRange(newAt[start], newAt[end]-newAt[start]) + " @@\n")
+		for _, line := range lines[start:end] {
+			out.WriteByte(line.kind)
+			out.WriteString(line.text)
+			if !strings.HasSuffix(line.text, "\n") {
+				out.WriteString("\n" + patchNoNewline + "\n")
+			}
+		}
+
+		i = end
+	}
+
+	return out.String()
+}
+
+// ExportPatch returns a unified diff of the changes made to a note
+// the file headers use the path relative to the vault, decrypted in privacy mode
+func (a *App) ExportPatch(original, current, path string) (string, error) {
+	path, err := a.resolveVaultPath(path)
+	if err != nil {
+		return "", err
+	}
+	if original == current {
+		return "", nil
+	}
+
+	rel, err := a.vaultRelPath(path)
+	if err != nil {
+		return "", err
+	}
+	if a.HasSecurity(a.rootPath) {
+		rel = a.GetDecryptedFullPath(rel, 0)
+	}
+	rel = filepath.ToSlash(rel)
+
+	return "--- a/" + rel + "\n+++ b/" + rel + "\n" + unifiedDiff(original, current), nil
+}
+
+// parsePatch parses the hunks of a single file unified diff
+func parsePatch(patch string) ([]patchHunk, error) {
+	var hunks []patchHunk
+	files := 0
+
+	patch = strings.ReplaceAll(patch, "\r\n", "\n")
+	if !strings.HasSuffix(patch, "\n") {
+		patch += "\n" // pasted patches often lose their last line ending
+	}
+
+	lines := splitLines(patch)
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "+++ ") {
+			files++
+			if files > 1 {
+				return nil, fmt.Errorf("patch_multiple_files")
+			}
+			continue
+		}
+
+		match := patchHunkHeader.FindStringSubmatch(line)
+		if match == nil {
+			continue // file headers, git extended headers, comments
+		}
+
+		hunk := patchHunk{header: strings.TrimRight(line, "\n")}
+		hunk.oldStart, _ = strconv.Atoi(match[1])
+		hunk.oldCount = 1
+		if match[2] != "" {
+			hunk.oldCount, _ = strconv.Atoi(match[2])
+		}
+		hunk.newStart, _ = strconv.Atoi(match[3])
+		hunk.newCount = 1
+		if match[4] != "" {
+			hunk.newCount, _ = strconv.Atoi(match[4])
+		}
+
+		// the body is read using the header counts, a removed line may start with "---"
+		oldLeft, newLeft := hunk.oldCount, hunk.newCount
+		var body strings.Builder
+		for (oldLeft > 0 || newLeft > 0) && i+1 < len(lines) {
+			i++
+			line := lines[i]
+			body.WriteString(line)
+			if strings.HasPrefix(line, patchNoNewline) {
+				continue
+			}
+			if line == "\n" {
+				line = " \n" // some tools strip the space of empty context lines
+			}
+
+			kind := line[0]
+			switch kind {
+			case ' ':
+				oldLeft--
+				newLeft--
+			case '-':
+				oldLeft--
+			case '+':
+				newLeft--
+			default:
+				return nil, fmt.Errorf("invalid_patch")
+			}
+			hunk.lines = append(hunk.lines, patchLine{kind, line[1:]})
+		}
+		if oldLeft != 0 || newLeft != 0 {
+			return nil, fmt.Errorf("invalid_patch")
+		}
+
+		// a marker right after the body still belongs to the last line
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], patchNoNewline) {
+			i++
+			body.WriteString(lines[i])
+		}
+		hunk.body = body.String()
+		markNoNewline(&hunk)
+
+		hunks = append(hunks, hunk)
+	}
+
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("invalid_patch")
+	}
+	return hunks, nil
+}
+
+// markNoNewline removes the line ending of lines followed by the "no newline" marker
+func markNoNewline(hunk *patchHunk) {
+	bodyLines := splitLines(hunk.body)
+	lineIdx := -1
+	for _, bodyLine := range bodyLines {
+		if strings.HasPrefix(bodyLine, patchNoNewline) {
+			if lineIdx >= 0 {
+				hunk.lines[lineIdx].text = strings.TrimSuffix(hunk.lines[lineIdx].text, "\n")
+			}
+			continue
+		}
+		lineIdx++
+	}
+}
+
+// hunkDiffs returns the diffs of a hunk, consecutive lines of the same kind being merged
+func hunkDiffs(hunk patchHunk) []diffmatchpatch.Diff {
+	var diffs []diffmatchpatch.Diff
+	for _, line := range hunk.lines {
+		op := diffmatchpatch.DiffEqual
+		switch line.kind {
+		case '-':
+			op = diffmatchpatch.DiffDelete
+		case '+':
+			op = diffmatchpatch.DiffInsert
+		}
+		if len(diffs) > 0 && diffs[len(diffs)-1].Type == op {
+			diffs[len(diffs)-1].Text += line.text
+			continue
+		}
+		diffs = append(diffs, diffmatchpatch.Diff{Type: op, Text: line.text})
+	}
+	return diffs
+}
+
+// lineOffset returns the byte offset of a line (0-based) in text, the end of text if it has less lines
+func lineOffset(text string, line int) int {
+	offset := 0
+	for i := 0; i < line; i++ {
+		next := strings.IndexByte(text[offset:], '\n')
+		if next == -1 {
+			return len(text)
+		}
+		offset += next + 1
+	}
+	return offset
+}
+
+// applyPatchHunks applies the hunks one by one with diffmatchpatch fuzzy matching
+// a hunk is either fully applied or rejected
+func applyPatchHunks(content string, hunks []patchHunk) (string, []PatchHunkReject) {
+	dmp := diffmatchpatch.New()
+	rejected := []PatchHunkReject{}
+	lineDelta := 0 // shift of the following hunks caused by the applied ones
+
+	for i, hunk := range hunks {
+		diffs := hunkDiffs(hunk)
+		patches := dmp.PatchMake(dmp.DiffText1(diffs), diffs)
+
+		// patches are relative to the hunk, move them where the hunk is expected in the content
+		expectedLine := hunk.oldStart - 1
+		if hunk.oldCount == 0 {
+			expectedLine = hunk.oldStart // an empty range points to the line before
+		}
+		offset := lineOffset(content, max(expectedLine+lineDelta, 0))
+		for j := range patches {
+			patches[j].Start1 += offset
+			patches[j].Start2 += offset
+		}
+
+		patched, results := dmp.PatchApply(patches, content)
+		applied := true
+		for _, result := range results {
+			applied = applied && result
+		}
+
+		if !applied {
+			rejected = append(rejected, PatchHunkReject{Index: i, Header: hunk.header, Text: hunk.body})
+			continue
+		}
+		content = patched
+		lineDelta += hunk.newCount - hunk.oldCount
+	}
+
+	return content, rejected
+}
+
+// ApplyPatch applies a unified diff to a note, hunks are located with fuzzy matching
+// when a hunk can't be placed nothing is written and the rejected hunks are reported
+func (a *App) ApplyPatch(path string, patch string) (PatchResult, error) {
+	hunks, err := parsePatch(patch)
+	if err != nil {
+		return PatchResult{}, err
+	}
+
+	content, err := a.ReadFile(path)
+	if err != nil {
+		return PatchResult{}, err
+	}
+
+	patched, rejected := applyPatchHunks(content, hunks)
+	result := PatchResult{
+		Hunks:    len(hunks),
+		Rejected: rejected,
+		Diff:     a.GetContentDiff(content, patched),
+	}
+	if len(rejected) > 0 {
+		return result, nil
+	}
+
+	err = a.WriteContentInFile(path, patched)
+	if err != nil {
+		return PatchResult{}, err
+	}
+	result.Applied = true
+	return result, nil
+}
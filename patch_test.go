@@ -0,0 +1,121 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const patchOriginal = "# title\n\none\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+
+func TestExportPatchUnifiedFormat(t *testing.T) {
+	a := newTestVault(t)
+	current := strings.Replace(patchOriginal, "two\n", "two edited\n", 1)
+	current = strings.Replace(current, "ten\n", "ten\neleven", 1)
+
+	patch, err := a.ExportPatch(patchOriginal, current, filepath.Join(a.rootPath, "notes", "note.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "--- a/notes/note.md\n+++ b/notes/note.md\n" +
+		"@@ -1,7 +1,7 @@\n # title\n \n one\n-two\n+two edited\n three\n four\n five\n" +
+		"@@ -10,3 +10,4 @@\n eight\n nine\n ten\n+eleven\n\\ No newline at end of file\n"
+	if patch != expected {
+		t.Fatalf("unexpected patch:\n%s\nexpected:\n%s", patch, expected)
+	}
+
+	if patch, _ := a.ExportPatch("same", "same", filepath.Join(a.rootPath, "note.md")); patch != "" {
+		t.Fatal("expected an empty patch for identical contents")
+	}
+}
+
+func TestApplyPatchRoundtrip(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+	a.WriteContentInFile(note, patchOriginal)
+	current := strings.Replace(patchOriginal, "three\n", "", 1)
+	current = strings.Replace(current, "nine\n", "nine\nnine and a half\n", 1)
+
+	patch, _ := a.ExportPatch(patchOriginal, current, note)
+	result, err := a.ApplyPatch(note, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := a.ReadFile(note)
+	if !result.Applied || result.Hunks != 2 || content != current {
+		t.Fatalf("unexpected result %+v, content %q", result, content)
+	}
+	if result.Diff.LinesAdded != 1 || result.Diff.LinesRemoved != 1 {
+		t.Fatalf("unexpected diff stats %+v", result.Diff)
+	}
+}
+
+func TestApplyPatchWithFuzz(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+	current := strings.Replace(patchOriginal, "seven\n", "SEVEN\n", 1)
+	patch, _ := a.ExportPatch(patchOriginal, current, note)
+
+	// the note moved on since the patch was made: lines added on top and a context line changed
+	a.WriteContentInFile(note, "intro\nmore intro\n"+strings.Replace(patchOriginal, "five\n", "5\n", 1))
+
+	result, err := a.ApplyPatch(note, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := a.ReadFile(note)
+	if !result.Applied || !strings.Contains(content, "six\nSEVEN\neight") || !strings.HasPrefix(content, "intro\n") {
+		t.Fatalf("expected the hunk to be placed with fuzz, got %+v %q", result, content)
+	}
+}
+
+func TestApplyPatchRejects(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+	a.WriteContentInFile(note, "completely\ndifferent\ncontent\n")
+
+	patch := "--- a/note.md\n+++ b/note.md\n@@ -1,3 +1,3 @@\n alpha beta gamma\n-delta epsilon\n+zeta eta\n theta iota kappa\n"
+	result, err := a.ApplyPatch(note, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Applied || len(result.Rejected) != 1 || result.Rejected[0].Header != "@@ -1,3 +1,3 @@" {
+		t.Fatalf("expected the hunk to be rejected, got %+v", result)
+	}
+	content, _ := a.ReadFile(note)
+	if content != "completely\ndifferent\ncontent\n" {
+		t.Fatal("a rejected patch must not change the note")
+	}
+
+	if _, err := a.ApplyPatch(note, "not a patch"); err == nil {
+		t.Fatal("expected an error for an invalid patch")
+	}
+}
+
+func TestPatchEncryptedNote(t *testing.T) {
+	a := newTestVault(t)
+	a.SetupPassword("testpassword", a.rootPath)
+	note, err := a.CreateFile(a.rootPath, "secret.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.WriteContentInFile(note, patchOriginal)
+	current := strings.Replace(patchOriginal, "one\n", "uno\n", 1)
+
+	patch, _ := a.ExportPatch(patchOriginal, current, note)
+	if !strings.HasPrefix(patch, "--- a/secret.mde\n") {
+		t.Fatalf("expected decrypted names in headers, got %q", patch)
+	}
+
+	result, err := a.ApplyPatch(note, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := a.ReadFile(note)
+	if !result.Applied || content != current {
+		t.Fatalf("unexpected content %q", content)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type RenameResult struct {
+	Path  string        `json:"path"`  // the new path of the note or folder
+	Links ReplaceResult `json:"links"` // the notes whose links were rewritten, Links.UndoID also undoes the rename
+}
+
+// renamePlan is a rename with the notes whose links it rewrites, their paths are the ones after the rename
+type renamePlan struct {
+	root    string
+	oldPath string
+	newPath string
+	files   []noteChange
+}
+
+// linkMove rewrites the links following a note or a folder moved from one path to another
+type linkMove struct {
+	resolver  *linkResolver
+	from      string // rel paths on disk
+	to        string
+	fromShown string // display paths
+	toShown   string
+}
+
+/**
+ * --- Rename with links
+ */
+// moved reports if a note is the moved note or inside the moved folder
+func (m *linkMove) moved(rel string) bool {
+	return rel == m.from || strings.HasPrefix(rel, m.from+string(filepath.Separator))
+}
+
+// relAfter returns the rel path on disk of a note once moved
+func (m *linkMove) relAfter(rel string) string {
+	if !m.moved(rel) {
+		return rel
+	}
+	return m.to + rel[len(m.from):]
+}
+
+// shownAfter returns the display path of a note once moved
+func (m *linkMove) shownAfter(rel string) string {
+	shown := m.resolver.shown[rel]
+	if !m.moved(rel) {
+		return shown
+	}
+	return m.toShown + shown[len(m.fromShown):]
+}
+
+// rewrite returns the content of the note at source with its links following the move
+func (m *linkMove) rewrite(content string, source string) (string, int) {
+	var out strings.Builder
+	last, count := 0, 0
+
+	for _, ref := range parseLinks(content) {
+		target, ok := m.resolver.resolve(source, ref.target, ref.kind)
+		if !ok {
+			continue // broken links are left as they are
+		}
+		text, changed := m.linkTarget(content, ref, source, target)
+		if !changed {
+			continue
+		}
+		out.WriteString(content[last:ref.targetStart])
+		out.WriteString(text)
+		last = ref.targetEnd
+		count++
+	}
+	if count == 0 {
+		return content, 0
+	}
+	out.WriteString(content[last:])
+
+	return out.String(), count
+}
+
+// linkTarget returns the new target of a link, false when the link still points to its note after the move
+// the style of the link is kept: name or path, extension, heading and alias
+func (m *linkMove) linkTarget(content string, ref noteLinkRef, source, target string) (string, bool) {
+	sourceMoved, targetMoved := m.moved(source), m.moved(target)
+	if !sourceMoved && !targetMoved {
+		return "", false
+	}
+
+	file := linkTargetFile(ref.target, ref.kind)
+	if file == "" {
+		return "", false // a link to a heading of the note itself
+	}
+	fragment := ""
+	if i := strings.IndexByte(ref.target, '#'); i != -1 {
+		fragment = ref.target[i:]
+	}
+	ext := ""
+	if isMDorMDE(file) {
+		ext = file[len(stripFileExt(file)):]
+	}
+	targetAfter := stripFileExt(m.shownAfter(target))
+
+	if ref.kind == "wiki" {
+		if !targetMoved {
+			return "", false // wiki links don't depend on the folder of the note holding them
+		}
+		key := strings.ToLower(stripFileExt(strings.TrimPrefix(file, "/")))
+		if strings.Contains(key, "/") {
+			if key == strings.ToLower(targetAfter) {
+				return "", false
+			}
+			prefix := ""
+			if strings.HasPrefix(file, "/") {
+				prefix = "/"
+			}
+			return prefix + targetAfter + ext + fragment, true
+		}
+
+		name := path.Base(targetAfter)
+		if key == strings.ToLower(name) {
+			return "", false
+		}
+		if len(m.resolver.byName[strings.ToLower(name)]) > 0 {
+			return targetAfter + ext + fragment, true // another note has this name
+		}
+		return name + ext + fragment, true
+	}
+
+	var linked string
+	if strings.HasPrefix(file, "/") {
+		if !targetMoved {
+			return "", false
+		}
+		linked = "/" + targetAfter + ext
+	} else {
+		sourceDir := path.Dir(m.shownAfter(source))
+		if strings.EqualFold(stripFileExt(path.Join(sourceDir, file)), targetAfter) {
+			return "", false
+		}
+		rel, err := filepath.Rel(filepath.FromSlash(sourceDir), filepath.FromSlash(targetAfter))
+		if err != nil {
+			return "", false
+		}
+		linked = filepath.ToSlash(rel) + ext
+	}
+	if ref.targetStart == 0 || content[ref.targetStart-1] != '<' {
+		linked = strings.ReplaceAll(linked, " ", "%20")
+	}
+	return linked + fragment, true
+}
+
+// planRename reads the notes whose links a rename rewrites, nothing is changed on disk
+func (a *App) planRename(oldPath, newPath, filename string, isFile bool) (renamePlan, error) {
+	root, err := a.resolveVaultPath(a.rootPath)
+	if err != nil {
+		return renamePlan{}, err
+	}
+	oldPath, err = a.resolveVaultChild(oldPath)
+	if err != nil {
+		return renamePlan{}, err
+	}
+	newPath, err = a.renamedPath(oldPath, newPath, filename, isFile)
+	if err != nil {
+		return renamePlan{}, err
+	}
+	if a.IsFileExists(newPath) {
+		return renamePlan{}, fmt.Errorf("file_already_exist")
+	}
+	from, _ := filepath.Rel(root, oldPath)
+	to, _ := filepath.Rel(root, newPath)
+
+	var docs []*indexedDoc
+	index := a.searchIndexFor(root)
+	if index != nil {
+		docs = index.allDocs()
+	} else {
+		docs = a.listVaultDocs(root)
+	}
+	move := &linkMove{
+		resolver:  a.newLinkResolver(docs),
+		from:      from,
+		to:        to,
+		fromShown: a.displayRelPath(from),
+		toShown:   a.displayRelPath(to),
+	}
+
+	plan := renamePlan{root: root, oldPath: oldPath, newPath: newPath}
+	for _, doc := range docs {
+		if doc.IsDir || (index != nil && len(doc.Links) == 0) {
+			continue // the index knows which notes have links
+		}
+		content, err := a.ReadFile(filepath.Join(root, doc.Path))
+		if err != nil {
+			continue
+		}
+		after, count := move.rewrite(content, doc.Path)
+		if count == 0 {
+			continue
+		}
+		plan.files = append(plan.files, noteChange{
+			rel:          move.relAfter(doc.Path),
+			name:         path.Base(move.shownAfter(doc.Path)),
+			before:       content,
+			after:        after,
+			replacements: count,
+		})
+	}
+
+	return plan, nil
+}
+
+// PreviewRenameLinks returns the notes whose links RenameFileWithLinks would rewrite, with the diffs
+// paths are the ones the notes have after the rename
+func (a *App) PreviewRenameLinks(oldPath, newPath, filename string, isFile bool) (ReplaceResult, error) {
+	plan, err := a.planRename(oldPath, newPath, filename, isFile)
+	if err != nil {
+		return ReplaceResult{}, err
+	}
+	return a.previewNoteChanges(plan.root, plan.files), nil
+}
+
+// RenameFileWithLinks renames or moves a note or a folder like RenameFile
+// and rewrites the wiki and markdown links pointing to it, or to the notes inside the folder
+// the links of the moved notes to other notes are rewritten too when their folder changes
+// UndoReplace with the returned Links.UndoID undoes the rename and the rewritten links at once,
+// also when a link can't be rewritten: the notes already rewritten are returned with the error
+func (a *App) RenameFileWithLinks(oldPath, newPath, filename string, isFile bool) (RenameResult, error) {
+	plan, err := a.planRename(oldPath, newPath, filename, isFile)
+	if err != nil {
+		return RenameResult{}, err
+	}
+	err = a.movePath(plan.oldPath, plan.newPath)
+	if err != nil {
+		return RenameResult{}, err
+	}
+
+	from, _ := filepath.Rel(plan.root, plan.oldPath)
+	to, _ := filepath.Rel(plan.root, plan.newPath)
+	links, err := a.applyNoteChanges(plan.root, plan.files, &replaceUndoRename{From: from, To: to})
+
+	return RenameResult{Path: plan.newPath, Links: links}, err
+}
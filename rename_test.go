@@ -0,0 +1,141 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenameFileWithLinks(t *testing.T) {
+	a := newTestVault(t)
+	plan := filepath.Join(a.rootPath, "plan.md")
+	home := filepath.Join(a.rootPath, "home.md")
+	a.WriteContentInFile(plan, "# Goals")
+	a.WriteContentInFile(home, "[[plan]], [[Plan#Goals|goals]], [p](plan.md) and `[[plan]]`")
+	a.getSearchIndex()
+
+	preview, err := a.PreviewRenameLinks(plan, a.rootPath, "roadmap", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview.Files) != 1 || preview.Files[0].Path != home || preview.Replacements != 3 || !preview.DryRun {
+		t.Fatalf("unexpected preview %+v", preview)
+	}
+	if content, _ := a.ReadFile(home); content != "[[plan]], [[Plan#Goals|goals]], [p](plan.md) and `[[plan]]`" || !a.IsFileExists(plan) {
+		t.Fatal("a preview must not change anything")
+	}
+
+	result, err := a.RenameFileWithLinks(plan, a.rootPath, "roadmap", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roadmap := filepath.Join(a.rootPath, "roadmap.md")
+	if result.Path != roadmap || result.Links.Replacements != 3 || result.Links.UndoID == "" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if content, _ := a.ReadFile(home); content != "[[roadmap]], [[roadmap#Goals|goals]], [p](roadmap.md) and `[[plan]]`" {
+		t.Fatalf("unexpected rewritten links %q", content)
+	}
+	if backlinks, _ := a.GetBacklinks(roadmap); len(backlinks) != 3 {
+		t.Fatalf("the links must point to the renamed note, got %+v", backlinks)
+	}
+
+	undone, err := a.UndoReplace(result.Links.UndoID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(undone.Files) != 1 || !a.IsFileExists(plan) || a.IsFileExists(roadmap) {
+		t.Fatal("the undo must restore the links and the name")
+	}
+	if content, _ := a.ReadFile(home); content != "[[plan]], [[Plan#Goals|goals]], [p](plan.md) and `[[plan]]`" {
+		t.Fatalf("unexpected restored links %q", content)
+	}
+}
+
+func TestRenameUndoKeptOnFailedRewrite(t *testing.T) {
+	a := newTestVault(t)
+	a.WriteContentInFile(filepath.Join(a.rootPath, "roadmap.md"), "# Goals") // already renamed from plan.md
+	os.Mkdir(filepath.Join(a.rootPath, "home.md"), 0700)                     // its links can't be rewritten
+
+	links, err := a.applyNoteChanges(a.rootPath, []noteChange{
+		{rel: "home.md", name: "home.md", before: "[[plan]]", after: "[[roadmap]]", replacements: 1},
+	}, &replaceUndoRename{From: "plan.md", To: "roadmap.md"})
+	if err == nil || links.UndoID == "" {
+		t.Fatalf("expected the error with an undo id for the rename, got %+v, %v", links, err)
+	}
+
+	if _, err := a.UndoReplace(links.UndoID); err != nil {
+		t.Fatal(err)
+	}
+	if !a.IsFileExists(filepath.Join(a.rootPath, "plan.md")) {
+		t.Fatal("the undo must move the note back")
+	}
+}
+
+func TestRenameFileWithLinksMovesFolder(t *testing.T) {
+	a := newTestVault(t)
+	os.MkdirAll(filepath.Join(a.rootPath, "projects"), 0700)
+	os.MkdirAll(filepath.Join(a.rootPath, "archive"), 0700)
+	home := filepath.Join(a.rootPath, "home.md")
+	a.WriteContentInFile(filepath.Join(a.rootPath, "projects", "my plan.md"), "[home](../home.md), [[home]] and [other](other.md)")
+	a.WriteContentInFile(filepath.Join(a.rootPath, "projects", "other.md"), "")
+	a.WriteContentInFile(home, "[[projects/my plan]] and [plan](projects/my%20plan.md)")
+	a.getSearchIndex()
+
+	result, err := a.RenameFileWithLinks(filepath.Join(a.rootPath, "projects"), filepath.Join(a.rootPath, "archive"), "projects", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Links.Replacements != 3 {
+		t.Fatalf("unexpected result %+v", result.Links)
+	}
+
+	if content, _ := a.ReadFile(home); content != "[[archive/projects/my plan]] and [plan](archive/projects/my%20plan.md)" {
+		t.Fatalf("unexpected links to the moved notes %q", content)
+	}
+	moved := filepath.Join(a.rootPath, "archive", "projects", "my plan.md")
+	if content, _ := a.ReadFile(moved); content != "[home](../../home.md), [[home]] and [other](other.md)" {
+		t.Fatalf("unexpected links of the moved note %q", content)
+	}
+}
+
+func TestRenameFileWithLinksUndoConflict(t *testing.T) {
+	a := newTestVault(t)
+	plan := filepath.Join(a.rootPath, "plan.md")
+	a.WriteContentInFile(plan, "")
+
+	result, err := a.RenameFileWithLinks(plan, a.rootPath, "roadmap", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.WriteContentInFile(plan, "a new note took the name")
+
+	if _, err := a.UndoReplace(result.Links.UndoID); err == nil || err.Error() != "undo_conflict" {
+		t.Fatalf("expected an undo_conflict error, got %v", err)
+	}
+	if _, err := a.RenameFileWithLinks(result.Path, a.rootPath, "plan", true); err == nil {
+		t.Fatal("expected an error when the name is taken")
+	}
+}
+
+func TestRenameFileWithLinksEncrypted(t *testing.T) {
+	a := newTestVault(t)
+	if response := a.SetupPassword("testpassword", a.rootPath); response != "ok" {
+		t.Fatal(response)
+	}
+	plan, _ := a.CreateFile(a.rootPath, "Plan")
+	home, _ := a.CreateFile(a.rootPath, "Home")
+	a.WriteContentInFile(home, "see [[Plan]]")
+	a.getSearchIndex()
+
+	result, err := a.RenameFileWithLinks(plan, a.rootPath, "Roadmap", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := a.ReadFile(home); content != "see [[Roadmap]]" {
+		t.Fatalf("links must use the decrypted names, got %q", content)
+	}
+	if a.displayName(result.Path) != "Roadmap.mde" || len(result.Links.Files) != 1 || result.Links.Files[0].Name != "Home.mde" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
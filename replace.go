@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const replaceUndoKept = 20
+
+type ReplaceOptions struct {
+	Search SearchOptions `json:"search"`
+	DryRun bool          `json:"dryRun"` // only preview the changes
+	Paths  []string      `json:"paths"`  // notes to change, every matching note when empty
+}
+
+type ReplaceFileChange struct {
+	Path         string `json:"path"`
+	Name         string `json:"name"`
+	Replacements int    `json:"replacements"`
+	Diff         Diff   `json:"diff"`
+}
+
+type ReplaceResult struct {
+	Files        []ReplaceFileChange `json:"files"`
+	Replacements int                 `json:"replacements"`
+	DryRun       bool                `json:"dryRun"`
+	UndoID       string              `json:"undoId"`            // empty for a dry run or when nothing changed
+	Skipped      []string            `json:"skipped,omitempty"` // undo: notes changed since the replace, left as they are
+}
+
+// replaceUndo records the content of the notes before and after a replace
+type replaceUndo struct {
+	ID     string             `json:"id"`
+	Time   int64              `json:"time"`
+	Files  []replaceUndoFile  `json:"files"`
+	Rename *replaceUndoRename `json:"rename,omitempty"` // the rename the links were rewritten for
+}
+
+type replaceUndoFile struct {
+	Path   string `json:"path"` // relative to the vault
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+type replaceUndoRename struct {
+	From string `json:"from"` // relative to the vault
+	To   string `json:"to"`
+}
+
+// noteChange is a note rewritten by a replace, a rename or a tag rename, read before anything is written
+type noteChange struct {
+	rel          string // relative to the vault
+	name         string
+	before       string
+	after        string
+	replacements int
+}
+
+/**
+ * --- Search and replace
+ */
+// replaceAll replaces the matches of the query in content, $1 or ${name} expand regex groups
+// with a scope, only the matches inside of it are replaced
+func (m *searchMatcher) replaceAll(content, replacement string) (string, int) {
+	matches := m.findAll(content, -1)
+	if len(matches) == 0 {
+		return content, 0
+	}
+
+	var out []byte
+	last := 0
+	for _, match := range matches {
+		out = append(out, content[last:match[0]]...)
+		if m.options.Regex {
+			out = m.pattern.ExpandString(out, replacement, content, match)
+		} else {
+			out = append(out, replacement...)
+		}
+		last = match[1]
+	}
+	out = append(out, content[last:]...)
+
+	return string(out), len(matches)
+}
+
+// getReplaceUndoDir returns the folder holding the undo records of replaces
+func (a *App) getReplaceUndoDir() string {
+	return filepath.Join(a.getTapeDir(), "replace")
+}
+
+// getReplaceUndoPath returns the path of an undo record, ids are timestamps
+func (a *App) getReplaceUndoPath(id string) (string, error) {
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return "", fmt.Errorf("undo_not_found")
+	}
+	return filepath.Join(a.getReplaceUndoDir(), id+".json"), nil
+}
+
+// saveReplaceUndo writes an undo record and drops the oldest ones
+func (a *App) saveReplaceUndo(undo replaceUndo) error {
+	data, err := json.Marshal(undo)
+	if err != nil {
+		return err
+	}
+	path, err := a.getReplaceUndoPath(undo.ID)
+	if err != nil {
+		return err
+	}
+	err = a.writeVaultData(path, data)
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(a.getReplaceUndoDir())
+	if err != nil {
+		return nil
+	}
+	// ids are timestamps of the same length, the name order is the time order
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	for i := 0; i < len(entries)-replaceUndoKept; i++ {
+		os.Remove(filepath.Join(a.getReplaceUndoDir(), entries[i].Name()))
+	}
+	return nil
+}
+
+// previewNoteChanges returns the changes of notes with their diffs, nothing is written
+func (a *App) previewNoteChanges(root string, changes []noteChange) ReplaceResult {
+	result := ReplaceResult{Files: []ReplaceFileChange{}, DryRun: true}
+	for _, change := range changes {
+		result.Files = append(result.Files, ReplaceFileChange{
+			Path:         filepath.Join(root, change.rel),
+			Name:         change.name,
+			Replacements: change.replacements,
+			Diff:         a.GetContentDiff(change.before, change.after),
+		})
+		result.Replacements += change.replacements
+	}
+	return result
+}
+
+// applyNoteChanges writes changed notes through WriteContentInFile and saves an undo record of them,
+// with the rename they follow if any. When a write fails the notes already written are kept:
+// the result lists them with the UndoID restoring them, along with the error
+func (a *App) applyNoteChanges(root string, changes []noteChange, rename *replaceUndoRename) (ReplaceResult, error) {
+	undo := replaceUndo{ID: strconv.FormatInt(time.Now().UnixNano(), 10), Time: time.Now().UnixMilli(), Rename: rename}
+	result := ReplaceResult{Files: []ReplaceFileChange{}}
+
+	var err error
+	for _, change := range changes {
+		path := filepath.Join(root, change.rel)
+		err = a.WriteContentInFile(path, change.after)
+		if err != nil {
+			break
+		}
+		undo.Files = append(undo.Files, replaceUndoFile{Path: change.rel, Before: change.before, After: change.after})
+
+		result.Files = append(result.Files, ReplaceFileChange{
+			Path:         path,
+			Name:         change.name,
+			Replacements: change.replacements,
+			Diff:         a.GetContentDiff(change.before, change.after),
+		})
+		result.Replacements += change.replacements
+	}
+
+	if len(undo.Files) == 0 && rename == nil {
+		return result, err
+	}
+	if saveErr := a.saveReplaceUndo(undo); saveErr != nil {
+		if err == nil {
+			err = saveErr
+		}
+		return result, err
+	}
+	result.UndoID = undo.ID
+
+	return result, err
+}
+
+// ReplaceInVault replaces the query in every note of the vault, through ReadFile and WriteContentInFile
+// so encrypted notes are handled and each change gets in the note history
+// with options.DryRun nothing is written and the diffs preview the changes
+// options.Paths restricts the notes changed, eg: to the ones kept by the user in the preview
+// the returned UndoID can be given to UndoReplace, also when a write fails: the notes already changed are returned with the error
+func (a *App) ReplaceInVault(query string, replacement string, options ReplaceOptions) (ReplaceResult, error) {
+	if query == "" {
+		return ReplaceResult{}, fmt.Errorf("empty_query")
+	}
+	root, err := a.resolveVaultPath(a.rootPath)
+	if err != nil {
+		return ReplaceResult{}, err
+	}
+	matcher, err := newSearchMatcher(query, options.Search)
+	if err != nil {
+		return ReplaceResult{}, err
+	}
+
+	var selected map[string]bool
+	if len(options.Paths) > 0 {
+		selected = make(map[string]bool)
+		for _, path := range options.Paths {
+			resolved, err := a.resolveVaultPath(path)
+			if err != nil {
+				return ReplaceResult{}, err
+			}
+			selected[resolved] = true
+		}
+	}
+
+	// the index narrows the notes to read like for a search, the notes themselves are read from disk
+	// so a replace never writes back content older than the note
+	var docs []*indexedDoc
+	if index := a.searchIndexFor(root); index != nil {
+		docs = index.allDocs()
+		if matcher.useIndex() {
+			docs = index.lookup(matcher.query)
+		}
+	} else {
+		docs = a.listVaultDocs(root)
+	}
+	var changes []noteChange
+	for _, doc := range docs {
+		path := filepath.Join(root, doc.Path)
+		if doc.IsDir || (selected != nil && !selected[path]) {
+			continue
+		}
+
+		content, err := a.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		replaced, count := matcher.replaceAll(content, replacement)
+		if count == 0 || replaced == content {
+			continue
+		}
+		changes = append(changes, noteChange{rel: doc.Path, name: doc.Name, before: content, after: replaced, replacements: count})
+	}
+
+	if options.DryRun {
+		return a.previewNoteChanges(root, changes), nil
+	}
+	return a.applyNoteChanges(root, changes, nil)
+}
+
+// UndoReplace restores the notes changed by a replace, or by RenameFileWithLinks and moves the note back
+// notes edited since then are skipped rather than losing the edits
+func (a *App) UndoReplace(undoID string) (ReplaceResult, error) {
+	path, err := a.getReplaceUndoPath(undoID)
+	if err != nil {
+		return ReplaceResult{}, err
+	}
+	data, err := a.readVaultData(path)
+	if err != nil {
+		return ReplaceResult{}, fmt.Errorf("undo_not_found")
+	}
+	var undo replaceUndo
+	err = json.Unmarshal(data, &undo)
+	if err != nil {
+		return ReplaceResult{}, err
+	}
+
+	// the rename is checked first, so it isn't left half undone
+	var renamedPath, originalPath string
+	if undo.Rename != nil {
+		renamedPath, err = a.resolveVaultChild(filepath.Join(a.rootPath, undo.Rename.To))
+		if err != nil {
+			return ReplaceResult{}, err
+		}
+		originalPath, err = a.resolveVaultChild(filepath.Join(a.rootPath, undo.Rename.From))
+		if err != nil {
+			return ReplaceResult{}, err
+		}
+		if !a.IsFileExists(renamedPath) || a.IsFileExists(originalPath) {
+			return ReplaceResult{}, fmt.Errorf("undo_conflict")
+		}
+	}
+
+	result := ReplaceResult{Files: []ReplaceFileChange{}}
+	for _, file := range undo.Files {
+		notePath := filepath.Join(a.rootPath, file.Path)
+		content, err := a.ReadFile(notePath)
+		if err != nil || content != file.After {
+			result.Skipped = append(result.Skipped, notePath)
+			continue
+		}
+
+		err = a.WriteContentInFile(notePath, file.Before)
+		if err != nil {
+			return ReplaceResult{}, err
+		}
+		result.Files = append(result.Files, ReplaceFileChange{
+			Path: notePath,
+			Name: a.displayName(notePath),
+			Diff: a.GetContentDiff(file.After, file.Before),
+		})
+	}
+
+	if undo.Rename != nil {
+		err = a.movePath(renamedPath, originalPath)
+		if err != nil {
+			return ReplaceResult{}, err
+		}
+	}
+
+	return result, os.Remove(path)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplaceAll(t *testing.T) {
+	tests := []struct {
+		query       string
+		options     SearchOptions
+		replacement string
+		content     string
+		expected    string
+	}{
+		{"cat", SearchOptions{}, "dog", "Cat and cat", "dog and dog"},
+		{"cat", SearchOptions{CaseSensitive: true}, "dog", "Cat and cat", "Cat and dog"},
+		{"cat", SearchOptions{WholeWord: true}, "dog", "cat concatenate", "dog concatenate"},
+		{`TODO\((\w+)\)`, SearchOptions{Regex: true}, "TODO[$1]", "a TODO(jane) b todo(bob)", "a TODO[jane] b TODO[bob]"},
+		{`(?P<first>\w+) (?P<last>\w+)`, SearchOptions{Regex: true, CaseSensitive: true}, "${last}, ${first}", "Ada Lovelace", "Lovelace, Ada"},
+		{"$1", SearchOptions{}, "$2", "cost $1", "cost $2"},
+	}
+
+	for _, tt := range tests {
+		matcher, err := newSearchMatcher(tt.query, tt.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		replaced, _ := matcher.replaceAll(tt.content, tt.replacement)
+		if replaced != tt.expected {
+			t.Fatalf("%q: expected %q, got %q", tt.query, tt.expected, replaced)
+		}
+	}
+}
+
+func TestReplaceInVault(t *testing.T) {
+	a := newTestVault(t)
+	noteA := filepath.Join(a.rootPath, "a.md")
+	noteB := filepath.Join(a.rootPath, "b.md")
+	a.WriteContentInFile(noteA, "the old name, the oldname")
+	a.WriteContentInFile(noteB, "old news")
+	a.WriteContentInFile(filepath.Join(a.rootPath, "c.md"), "nothing here")
+
+	preview, err := a.ReplaceInVault("old", "new", ReplaceOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview.Files) != 2 || preview.Replacements != 3 || preview.UndoID != "" {
+		t.Fatalf("unexpected preview %+v", preview)
+	}
+	if preview.Files[0].Diff.Edit == 0 {
+		t.Fatal("the preview must hold the diff of each note")
+	}
+	if content, _ := a.ReadFile(noteA); content != "the old name, the oldname" {
+		t.Fatal("a dry run must not write")
+	}
+
+	result, err := a.ReplaceInVault("old", "new", ReplaceOptions{Paths: []string{noteA}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Files) != 1 || result.UndoID == "" {
+		t.Fatalf("expected only the selected note to change, got %+v", result)
+	}
+	if content, _ := a.ReadFile(noteB); content != "old news" {
+		t.Fatal("a note left out of the selection must not change")
+	}
+	if content, _ := a.ReadFile(noteA); content != "the new name, the newname" {
+		t.Fatalf("unexpected replaced content %q", content)
+	}
+
+	undone, err := a.UndoReplace(result.UndoID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := a.ReadFile(noteA); content != "the old name, the oldname" || len(undone.Files) != 1 {
+		t.Fatalf("expected the replace to be undone, got %q", content)
+	}
+	if _, err := a.UndoReplace(result.UndoID); err == nil {
+		t.Fatal("an undo record must be used once")
+	}
+}
+
+func TestReplaceInVaultScope(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+	a.WriteContentInFile(note, "---\ntitle: old\n---\n# old heading\nold text, `old code` and [old](old.md)\n")
+
+	options := ReplaceOptions{Search: SearchOptions{Scope: mdCode}, DryRun: true}
+	preview, err := a.ReplaceInVault("old", "new", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Replacements != 1 {
+		t.Fatalf("the preview must only count the matches in the scope, got %d", preview.Replacements)
+	}
+
+	options.DryRun = false
+	if _, err := a.ReplaceInVault("old", "new", options); err != nil {
+		t.Fatal(err)
+	}
+	expected := "---\ntitle: old\n---\n# old heading\nold text, `new code` and [old](old.md)\n"
+	if content, _ := a.ReadFile(note); content != expected {
+		t.Fatalf("only the code must change, expected %q, got %q", expected, content)
+	}
+}
+
+func TestUndoReplaceKeepsLaterEdits(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+	a.WriteContentInFile(note, "old")
+
+	result, _ := a.ReplaceInVault("old", "new", ReplaceOptions{})
+	a.WriteContentInFile(note, "new and edited")
+
+	undone, err := a.UndoReplace(result.UndoID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(undone.Skipped) != 1 {
+		t.Fatalf("an edited note must be skipped, got %+v", undone)
+	}
+	if content, _ := a.ReadFile(note); content != "new and edited" {
+		t.Fatal("later edits must be kept")
+	}
+	if _, err := a.UndoReplace("../../tape"); err == nil {
+		t.Fatal("expected an error for an invalid undo id")
+	}
+}
+
+func TestApplyNoteChangesKeepsPartialUndo(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+	a.WriteContentInFile(note, "old")
+	os.Mkdir(filepath.Join(a.rootPath, "folder.md"), 0700) // can't be written as a note
+
+	result, err := a.applyNoteChanges(a.rootPath, []noteChange{
+		{rel: "note.md", name: "note.md", before: "old", after: "new", replacements: 1},
+		{rel: "folder.md", name: "folder.md", before: "old", after: "new", replacements: 1},
+	}, nil)
+	if err == nil {
+		t.Fatal("expected the failed write to be reported")
+	}
+	if len(result.Files) != 1 || result.Replacements != 1 || result.UndoID == "" {
+		t.Fatalf("expected the written note with its undo id, got %+v", result)
+	}
+
+	if _, err := a.UndoReplace(result.UndoID); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := a.ReadFile(note); content != "old" {
+		t.Fatalf("the written note must be restored, got %q", content)
+	}
+}
+
+func TestReplaceInVaultEncrypted(t *testing.T) {
+	a := newTestVault(t)
+	if response := a.SetupPassword("testpassword", a.rootPath); response != "ok" {
+		t.Fatal(response)
+	}
+	note := filepath.Join(a.rootPath, "MDE1note.mde")
+	a.WriteContentInFile(note, "secret plan")
+
+	result, err := a.ReplaceInVault("plan", "project", ReplaceOptions{})
+	if err != nil || len(result.Files) != 1 {
+		t.Fatalf("expected the encrypted note to be replaced, got %+v %v", result, err)
+	}
+	if content, _ := a.ReadFile(note); content != "secret project" {
+		t.Fatalf("unexpected content %q", content)
+	}
+
+	entries, _ := os.ReadDir(a.getReplaceUndoDir())
+	for _, entry := range entries {
+		raw, _ := os.ReadFile(filepath.Join(a.getReplaceUndoDir(), entry.Name()))
+		if strings.Contains(string(raw), "secret") {
+			t.Fatal("the undo record is stored in plain text")
+		}
+	}
+}
@@ -0,0 +1,659 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	searchIndexVersion   = 4
+	searchIndexSaveDelay = 2 * time.Second
+	searchContentCache   = 64 << 20 // bytes of note contents kept in memory for searches
+)
+
+type indexedDoc struct {
+	Path    string        `json:"path"` // relative to the vault
+	Name    string        `json:"name"` // decrypted in privacy mode
+	IsDir   bool          `json:"isDir"`
+	ModTime int64         `json:"modTime"` // unix nano, detects changes made while tape was closed
+	Size    int64         `json:"size"`
+	Terms   []string      `json:"terms,omitempty"` // distinct terms of the content, needed to unindex it
+	Links   []indexedLink `json:"links,omitempty"` // links written in the note
+	Meta    *NoteMeta     `json:"meta,omitempty"`  // title, tags and date of the front matter
+	Tags    []string      `json:"tags,omitempty"`  // lowercased tags, inline and from the front matter
+}
+
+// searchIndexData is the persisted part of the index
+type searchIndexData struct {
+	Version  int                 `json:"version"`
+	NextID   int                 `json:"nextId"`
+	Docs     map[int]*indexedDoc `json:"docs"`
+	Postings map[string][]int    `json:"postings"` // term => sorted doc ids
+}
+
+// searchIndex is an inverted index of the notes content, stored in .tape/index.json
+type searchIndex struct {
+	mu        sync.RWMutex
+	data      searchIndexData
+	root      string
+	ids       map[string]int      // path => doc id
+	terms     []string            // sorted terms, nil when outdated
+	grams     map[string][]string // trigram => terms holding it, for substring lookups, nil when outdated
+	docs      []*indexedDoc       // every doc sorted by path, nil when outdated
+	contents  map[int]string      // doc id => content of the notes read, kept in memory only
+	cached    int                 // bytes held by contents
+	saveTimer *time.Timer
+	watcher   *fsnotify.Watcher
+}
+
+/**
+ * --- Search index
+ */
+// tokenize splits a text in lowercased words made of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// distinctTerms returns the distinct tokens of a text
+func distinctTerms(text string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, token := range tokenize(text) {
+		if !seen[token] {
+			seen[token] = true
+			terms = append(terms, token)
+		}
+	}
+	return terms
+}
+
+func newSearchIndex(root string) *searchIndex {
+	return &searchIndex{
+		root: root,
+		data: searchIndexData{
+			Version:  searchIndexVersion,
+			Docs:     make(map[int]*indexedDoc),
+			Postings: make(map[string][]int),
+		},
+		ids:      make(map[string]int),
+		contents: make(map[int]string),
+	}
+}
+
+// getSearchIndexPath returns the path of the persisted index
+func (a *App) getSearchIndexPath() string {
+	return filepath.Join(a.getTapeDir(), "index.json")
+}
+
+// getSearchIndex returns the index of the opened vault, loading and reconciling it on first use
+// nil is returned while the vault is locked, callers fall back on a walk of the vault
+func (a *App) getSearchIndex() *searchIndex {
+	if a.rootPath == "" || (a.HasSecurity(a.rootPath) && a.masterkey == nil) {
+		return nil
+	}
+
+	// one load at a time, the ones waiting get the loaded index
+	a.searchLoad.Lock()
+	defer a.searchLoad.Unlock()
+	if index := a.loadedSearchIndex(); index != nil {
+		return index
+	}
+	return a.loadSearchIndex(a.rootPath)
+}
+
+// loadedSearchIndex returns the index of the opened vault when it is loaded, without loading it
+func (a *App) loadedSearchIndex() *searchIndex {
+	a.searchMu.Lock()
+	defer a.searchMu.Unlock()
+	if a.search != nil && a.search.root == a.rootPath {
+		return a.search
+	}
+	return nil
+}
+
+// preloadSearchIndex loads the index in the background, "index:ready" is emitted once it is loaded
+// eg: the file tree is shown first and gets the properties of the notes after
+func (a *App) preloadSearchIndex() {
+	a.searchPreload.Add(1)
+	go func() {
+		defer a.searchPreload.Done()
+		if a.loadedSearchIndex() != nil {
+			return
+		}
+		if a.getSearchIndex() != nil {
+			a.emitEvent("index:ready", nil)
+		}
+	}()
+}
+
+// loadSearchIndex reads the saved index of a vault and reconciles it with the vault
+// the watcher starts first: notes changed while the vault is walked are reindexed too
+func (a *App) loadSearchIndex(root string) *searchIndex {
+	index := newSearchIndex(root)
+	data, err := a.readVaultData(a.getSearchIndexPath())
+	if err == nil {
+		var saved searchIndexData
+		if json.Unmarshal(data, &saved) == nil && saved.Version == searchIndexVersion {
+			index.data = saved
+			for id, doc := range saved.Docs {
+				index.ids[doc.Path] = id
+			}
+		}
+	}
+
+	a.startVaultWatcher(index)
+	a.reconcileSearchIndex(index)
+
+	a.searchMu.Lock()
+	defer a.searchMu.Unlock()
+	if a.rootPath != root {
+		// another vault was opened meanwhile
+		if index.watcher != nil {
+			index.watcher.Close()
+		}
+		return nil
+	}
+	a.closeSearchIndexLocked()
+	a.search = index
+
+	return index
+}
+
+// closeSearchIndex saves and drops the index, eg: when the vault changes or tape is closed
+func (a *App) closeSearchIndex() {
+	a.searchPreload.Wait()
+	a.searchMu.Lock()
+	defer a.searchMu.Unlock()
+	a.closeSearchIndexLocked()
+}
+
+func (a *App) closeSearchIndexLocked() {
+	if a.search == nil {
+		return
+	}
+	if a.search.watcher != nil {
+		a.search.watcher.Close()
+	}
+	a.saveSearchIndex(a.search)
+	a.search = nil
+}
+
+// scheduleSearchIndexSave debounces the writing of the index on disk
+func (a *App) scheduleSearchIndexSave(index *searchIndex) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	if index.saveTimer != nil {
+		index.saveTimer.Stop()
+	}
+	index.saveTimer = time.AfterFunc(searchIndexSaveDelay, func() {
+		a.saveSearchIndex(index)
+	})
+}
+
+// saveSearchIndex writes the index on disk, encrypted in privacy mode
+// note: the index of a vault that is not the opened one anymore is dropped, encryption settings
+// and key are the ones of the opened vault. It is reconciled the next time the vault is opened
+func (a *App) saveSearchIndex(index *searchIndex) error {
+	if index.root != a.rootPath {
+		return nil
+	}
+
+	index.mu.Lock()
+	if index.saveTimer != nil {
+		index.saveTimer.Stop()
+		index.saveTimer = nil
+	}
+	data, err := json.Marshal(index.data)
+	index.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return a.writeVaultData(filepath.Join(index.root, ".tape", "index.json"), data)
+}
+
+// isIndexable reports if a path inside the vault belongs in the index (no hidden parts, no config)
+func isIndexable(rel string) bool {
+	if rel == "." || rel == "tape.json" {
+		return false
+	}
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if strings.HasPrefix(part, ".") {
+			return false
+		}
+	}
+	return true
+}
+
+// reconcileSearchIndex brings the index up to date with the vault, only changed notes are read
+func (a *App) reconcileSearchIndex(index *searchIndex) {
+	seen := make(map[string]bool)
+
+	filepath.Walk(index.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(index.root, path)
+		if err != nil || rel == "." {
+			return nil
+		}
+		if !isIndexable(rel) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() && !isMDorMDE(info.Name()) {
+			return nil
+		}
+
+		seen[rel] = true
+		a.indexPath(index, path, info)
+		return nil
+	})
+
+	index.mu.Lock()
+	var removed []string
+	for path := range index.ids {
+		if !seen[path] {
+			removed = append(removed, path)
+		}
+	}
+	for _, path := range removed {
+		index.removeLocked(path)
+	}
+	index.mu.Unlock()
+
+	a.saveSearchIndex(index)
+}
+
+// indexPath (re)indexes a note or a folder unless it didn't change since it was indexed
+func (a *App) indexPath(index *searchIndex, path string, info os.FileInfo) {
+	rel, err := filepath.Rel(index.root, path)
+	if err != nil || !isIndexable(rel) {
+		return
+	}
+
+	index.mu.RLock()
+	id, known := index.ids[rel]
+	unchanged := known && index.data.Docs[id].ModTime == info.ModTime().UnixNano() && index.data.Docs[id].Size == info.Size()
+	index.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	name := info.Name()
+	if a.HasSecurity(index.root) {
+		name = a.GetDecryptedFileName(path)
+	}
+
+	doc := &indexedDoc{
+		Path:    rel,
+		Name:    name,
+		IsDir:   info.IsDir(),
+		ModTime: info.ModTime().UnixNano(),
+		Size:    info.Size(),
+	}
+	var content string
+	if !info.IsDir() {
+		content, err = a.ReadFile(path)
+		if err != nil {
+			return
+		}
+		doc.Terms = distinctTerms(content)
+		doc.Links = indexLinks(content)
+		doc.Meta = noteMeta(content)
+		doc.Tags = noteTags(content)
+	}
+
+	index.mu.Lock()
+	index.removeLocked(rel)
+	index.addLocked(doc)
+	index.mu.Unlock()
+
+	if !info.IsDir() {
+		index.cacheContent(rel, doc, content)
+	}
+}
+
+// addLocked adds a document and its postings, index.mu must be held
+func (index *searchIndex) addLocked(doc *indexedDoc) {
+	id := index.data.NextID
+	index.data.NextID++
+	index.data.Docs[id] = doc
+	index.ids[doc.Path] = id
+	index.docs = nil
+
+	for _, term := range doc.Terms {
+		postings, known := index.data.Postings[term]
+		if !known {
+			index.terms, index.grams = nil, nil
+		}
+		// ids only grow so appending keeps postings sorted
+		index.data.Postings[term] = append(postings, id)
+	}
+}
+
+// removeLocked removes a document and its postings, index.mu must be held
+func (index *searchIndex) removeLocked(path string) {
+	id, known := index.ids[path]
+	if !known {
+		return
+	}
+
+	for _, term := range index.data.Docs[id].Terms {
+		postings := index.data.Postings[term]
+		i := sort.SearchInts(postings, id)
+		if i < len(postings) && postings[i] == id {
+			postings = append(postings[:i], postings[i+1:]...)
+		}
+		if len(postings) == 0 {
+			delete(index.data.Postings, term)
+			index.terms, index.grams = nil, nil
+		} else {
+			index.data.Postings[term] = postings
+		}
+	}
+
+	index.cached -= len(index.contents[id])
+	delete(index.contents, id)
+	delete(index.data.Docs, id)
+	delete(index.ids, path)
+	index.docs = nil
+}
+
+// removeTree removes a path and everything under it from the index
+func (index *searchIndex) removeTree(rel string) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	var removed []string
+	for path := range index.ids {
+		if path == rel || strings.HasPrefix(path, rel+string(filepath.Separator)) {
+			removed = append(removed, path)
+		}
+	}
+	for _, path := range removed {
+		index.removeLocked(path)
+	}
+}
+
+// sortedTermsLocked returns the sorted terms, rebuilt when terms were added or removed
+func (index *searchIndex) sortedTermsLocked() []string {
+	if index.terms == nil {
+		index.terms = make([]string, 0, len(index.data.Postings))
+		for term := range index.data.Postings {
+			index.terms = append(index.terms, term)
+		}
+		sort.Strings(index.terms)
+	}
+	return index.terms
+}
+
+// trigrams returns the distinct sequences of 3 runes of a term
+func trigrams(term string) []string {
+	runes := []rune(term)
+	seen := make(map[string]bool)
+	var grams []string
+	for i := 0; i+3 <= len(runes); i++ {
+		gram := string(runes[i : i+3])
+		if !seen[gram] {
+			seen[gram] = true
+			grams = append(grams, gram)
+		}
+	}
+	return grams
+}
+
+// termsContainingLocked returns the terms holding token, index.mu must be held
+// only the terms sharing the rarest trigram of the token are checked, every term for shorter tokens
+func (index *searchIndex) termsContainingLocked(token string) []string {
+	candidates := index.sortedTermsLocked()
+	if grams := trigrams(token); len(grams) > 0 {
+		if index.grams == nil {
+			index.grams = make(map[string][]string)
+			for _, term := range candidates {
+				for _, gram := range trigrams(term) {
+					index.grams[gram] = append(index.grams[gram], term)
+				}
+			}
+		}
+		candidates = index.grams[grams[0]]
+		for _, gram := range grams[1:] {
+			if terms := index.grams[gram]; len(terms) < len(candidates) {
+				candidates = terms
+			}
+		}
+	}
+
+	var terms []string
+	for _, term := range candidates {
+		if strings.Contains(term, token) {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// lookup returns the documents holding, for every token of the query, a term containing it
+// like the content search, "ell" finds "hello". The cost depends on the number of terms
+// sharing a trigram with the tokens and of matching documents, not on the size of the vault
+func (index *searchIndex) lookup(query string) []*indexedDoc {
+	tokens := tokenize(query)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	var matching map[int]bool
+	for _, token := range tokens {
+		found := make(map[int]bool)
+		for _, term := range index.termsContainingLocked(token) {
+			for _, id := range index.data.Postings[term] {
+				if matching == nil || matching[id] {
+					found[id] = true
+				}
+			}
+		}
+		matching = found
+		if len(matching) == 0 {
+			return nil
+		}
+	}
+
+	docs := make([]*indexedDoc, 0, len(matching))
+	for id := range matching {
+		docs = append(docs, index.data.Docs[id])
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].Path < docs[j].Path
+	})
+	return docs
+}
+
+// allDocs returns every indexed note and folder, ordered by path
+// the slice is shared until the index changes, callers must not modify it
+func (index *searchIndex) allDocs() []*indexedDoc {
+	index.mu.RLock()
+	docs := index.docs
+	index.mu.RUnlock()
+	if docs != nil {
+		return docs
+	}
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	if index.docs == nil {
+		index.docs = make([]*indexedDoc, 0, len(index.data.Docs))
+		for _, doc := range index.data.Docs {
+			index.docs = append(index.docs, doc)
+		}
+		sort.Slice(index.docs, func(i, j int) bool {
+			return index.docs[i].Path < index.docs[j].Path
+		})
+	}
+	return index.docs
+}
+
+// cacheContent keeps the content of a note read for doc, while the memory budget allows it
+// a content read before the note was reindexed is dropped: doc isn't the indexed one anymore
+func (index *searchIndex) cacheContent(rel string, doc *indexedDoc, content string) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	id, known := index.ids[rel]
+	if !known || index.data.Docs[id] != doc || index.cached+len(content) > searchContentCache {
+		return
+	}
+	if _, cached := index.contents[id]; !cached {
+		index.contents[id] = content
+		index.cached += len(content)
+	}
+}
+
+// readIndexedNote returns the content of a note, from the memory of the index when it was read before
+// searches read the notes through it so they are not read and decrypted again on every query
+func (a *App) readIndexedNote(index *searchIndex, path string) (string, error) {
+	if index == nil {
+		return a.ReadFile(path)
+	}
+	rel, err := filepath.Rel(index.root, path)
+	if err != nil {
+		return a.ReadFile(path)
+	}
+
+	index.mu.RLock()
+	id, known := index.ids[rel]
+	doc := index.data.Docs[id]
+	content, cached := index.contents[id]
+	index.mu.RUnlock()
+	if known && cached {
+		return content, nil
+	}
+
+	content, err = a.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if known {
+		index.cacheContent(rel, doc, content)
+	}
+	return content, nil
+}
+
+// updateSearchIndex reindexes paths changed by tape itself, removed paths are unindexed
+func (a *App) updateSearchIndex(paths ...string) {
+	a.searchMu.Lock()
+	index := a.search
+	a.searchMu.Unlock()
+	if index == nil {
+		return // not loaded yet, it is reconciled on first use
+	}
+
+	for _, path := range paths {
+		a.syncIndexedPath(index, path)
+	}
+	a.scheduleSearchIndexSave(index)
+}
+
+// syncIndexedPath updates the index for a path that may have been created, changed or removed
+func (a *App) syncIndexedPath(index *searchIndex, path string) {
+	rel, err := filepath.Rel(index.root, path)
+	if err != nil || !isIndexable(rel) {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		index.removeTree(rel)
+		return
+	}
+
+	if !info.IsDir() {
+		if isMDorMDE(info.Name()) {
+			a.indexPath(index, path, info)
+		}
+		return
+	}
+
+	// a created or moved folder: index everything under it
+	filepath.Walk(path, func(child string, childInfo os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		childRel, _ := filepath.Rel(index.root, child)
+		if !isIndexable(childRel) {
+			if childInfo.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if childInfo.IsDir() || isMDorMDE(childInfo.Name()) {
+			a.indexPath(index, child, childInfo)
+		}
+		return nil
+	})
+}
+
+// startVaultWatcher keeps the index up to date with changes made outside of tape
+func (a *App) startVaultWatcher(index *searchIndex) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return // the index is still reconciled each time the vault is opened
+	}
+	index.watcher = watcher
+	watchTree(watcher, index.root)
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				rel, err := filepath.Rel(index.root, event.Name)
+				if err != nil || !isIndexable(rel) {
+					continue // tape data and config
+				}
+				if event.Has(fsnotify.Create) {
+					info, err := os.Stat(event.Name)
+					if err == nil && info.IsDir() {
+						watchTree(watcher, event.Name)
+					}
+				}
+				a.syncIndexedPath(index, event.Name)
+				a.scheduleSearchIndexSave(index)
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// watchTree adds a folder and its sub folders to the watcher, fsnotify is not recursive
+func watchTree(watcher *fsnotify.Watcher, root string) {
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		watcher.Add(path)
+		return nil
+	})
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens := tokenize("Hello, World! état-2026 #todo")
+	expected := []string{"hello", "world", "état", "2026", "todo"}
+	if strings.Join(tokens, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected %v, got %v", expected, tokens)
+	}
+}
+
+func TestSearchIndexLookup(t *testing.T) {
+	index := newSearchIndex("/vault")
+	index.addLocked(&indexedDoc{Path: "a.md", Terms: distinctTerms("golang search index")})
+	index.addLocked(&indexedDoc{Path: "b.md", Terms: distinctTerms("go to the market")})
+
+	if docs := index.lookup("go"); len(docs) != 2 {
+		t.Fatalf("expected both notes for a prefix, got %d", len(docs))
+	}
+	if docs := index.lookup("go ind"); len(docs) != 1 || docs[0].Path != "a.md" {
+		t.Fatalf("expected every token to match, got %v", docs)
+	}
+	if docs := index.lookup("arke"); len(docs) != 1 || docs[0].Path != "b.md" {
+		t.Fatalf("expected a note for a token inside a word, got %v", docs)
+	}
+	if docs := index.lookup("ol"); len(docs) != 1 || docs[0].Path != "a.md" {
+		t.Fatalf("expected a note for a token shorter than a trigram, got %v", docs)
+	}
+	if docs := index.lookup("lang ark"); len(docs) != 0 {
+		t.Fatalf("expected no note when the tokens are in different notes, got %v", docs)
+	}
+
+	index.removeLocked("a.md")
+	if docs := index.lookup("golang"); len(docs) != 0 {
+		t.Fatal("a removed note must not be found anymore")
+	}
+	if _, known := index.data.Postings["golang"]; known {
+		t.Fatal("empty postings must be dropped")
+	}
+}
+
+func TestSearchIndexFollowsChanges(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+	a.WriteContentInFile(note, "first draft")
+
+	index := a.getSearchIndex()
+	if index == nil {
+		t.Fatal("expected an index for an opened vault")
+	}
+	if len(index.lookup("draft")) != 1 {
+		t.Fatal("existing notes must be indexed when the index is created")
+	}
+
+	a.WriteContentInFile(note, "second version")
+	if len(index.lookup("draft")) != 0 || len(index.lookup("second")) != 1 {
+		t.Fatal("a written note must be reindexed")
+	}
+
+	renamed, err := a.RenameFile(note, a.rootPath, "renamed.md", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if docs := index.lookup("second"); len(docs) != 1 || docs[0].Path != "renamed.md" {
+		t.Fatalf("expected the renamed note, got %v", docs)
+	}
+
+	a.DeleteFile(renamed)
+	if len(index.lookup("second")) != 0 {
+		t.Fatal("a deleted note must be unindexed")
+	}
+}
+
+func TestSearchIndexAllDocs(t *testing.T) {
+	index := newSearchIndex("/vault")
+	index.addLocked(&indexedDoc{Path: "b.md"})
+	index.addLocked(&indexedDoc{Path: "a.md"})
+
+	docs := index.allDocs()
+	if len(docs) != 2 || docs[0].Path != "a.md" {
+		t.Fatalf("expected the notes ordered by path, got %v", docs)
+	}
+	if again := index.allDocs(); &again[0] != &docs[0] {
+		t.Fatal("the docs must be kept until the index changes")
+	}
+
+	index.addLocked(&indexedDoc{Path: "c.md"})
+	if docs := index.allDocs(); len(docs) != 3 {
+		t.Fatalf("an added note must be listed, got %v", docs)
+	}
+	index.removeLocked("a.md")
+	if docs := index.allDocs(); len(docs) != 2 || docs[0].Path != "b.md" {
+		t.Fatalf("a removed note must not be listed, got %v", docs)
+	}
+}
+
+func TestSearchIndexContentCache(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "note.md")
+	a.WriteContentInFile(note, "first draft")
+	index := a.getSearchIndex()
+
+	if content, _ := a.readIndexedNote(index, note); content != "first draft" {
+		t.Fatalf("unexpected content %q", content)
+	}
+	if len(index.contents) != 1 {
+		t.Fatal("the content of the note must be kept")
+	}
+
+	a.WriteContentInFile(note, "second version")
+	if content, _ := a.readIndexedNote(index, note); content != "second version" {
+		t.Fatalf("a reindexed note must not be read from the cache, got %q", content)
+	}
+
+	a.DeleteFile(note)
+	if len(index.contents) != 0 || index.cached != 0 {
+		t.Fatal("the content of a deleted note must be dropped")
+	}
+}
+
+func TestSearchFilesInsideWords(t *testing.T) {
+	a := newTestVault(t)
+	a.WriteContentInFile(filepath.Join(a.rootPath, "note.md"), "hello world")
+	a.getSearchIndex() // loaded, so the index narrows the notes
+
+	results, err := a.SearchFiles(a.rootPath, "ell", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].MatchType != "content" {
+		t.Fatalf("expected the note holding \"hello\", got %+v", results)
+	}
+}
+
+func TestSearchFilesUsesIndex(t *testing.T) {
+	a := newTestVault(t)
+	os.Mkdir(filepath.Join(a.rootPath, "projects"), 0700)
+	a.WriteContentInFile(filepath.Join(a.rootPath, "projects", "plan.md"), "Ship the search index")
+
+	results, err := a.SearchFiles(a.rootPath, "search ind", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].MatchType != "content" || results[0].MatchText != "search ind" {
+		t.Fatalf("unexpected content results %+v", results)
+	}
+
+	results, _ = a.SearchFiles(a.rootPath, "proj", SearchOptions{})
+	if len(results) != 1 || results[0].MatchType != "foldername" {
+		t.Fatalf("unexpected name results %+v", results)
+	}
+}
+
+func TestSearchIndexReconcilesOnLoad(t *testing.T) {
+	a := newTestVault(t)
+	a.WriteContentInFile(filepath.Join(a.rootPath, "kept.md"), "kept note")
+	a.WriteContentInFile(filepath.Join(a.rootPath, "gone.md"), "gone note")
+	a.getSearchIndex()
+	a.closeSearchIndex()
+
+	// changes made while tape was closed
+	os.Remove(filepath.Join(a.rootPath, "gone.md"))
+	os.WriteFile(filepath.Join(a.rootPath, "added.md"), []byte("added note"), 0600)
+
+	index := a.getSearchIndex()
+	if len(index.lookup("kept")) != 1 || len(index.lookup("added")) != 1 || len(index.lookup("gone")) != 0 {
+		t.Fatal("the saved index must be reconciled with the vault")
+	}
+}
+
+func TestSearchIndexEncrypted(t *testing.T) {
+	a := newTestVault(t)
+	if response := a.SetupPassword("testpassword", a.rootPath); response != "ok" {
+		t.Fatal(response)
+	}
+	a.WriteContentInFile(filepath.Join(a.rootPath, "MDE1note.mde"), "very secret content")
+	a.getSearchIndex()
+	a.closeSearchIndex()
+
+	raw, err := os.ReadFile(a.getSearchIndexPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "secret") || strings.Contains(string(raw), "postings") {
+		t.Fatal("the index is stored in plain text")
+	}
+
+	if len(a.getSearchIndex().lookup("secret")) != 1 {
+		t.Fatal("the encrypted index must be readable back")
+	}
+
+	a.masterkey = nil
+	if a.getSearchIndex() != nil {
+		t.Fatal("a locked vault must not be indexed")
+	}
+}
+
+func TestHasSecurityCacheInvalidatedBySaveConfig(t *testing.T) {
+	a := newTestVault(t)
+	if a.HasSecurity(a.rootPath) {
+		t.Fatal("a new vault has no security")
+	}
+	if response := a.SetupPassword("testpassword", a.rootPath); response != "ok" {
+		t.Fatal(response)
+	}
+	if !a.HasSecurity(a.rootPath) {
+		t.Fatal("the cached value must be refreshed when the config is saved")
+	}
+
+	// cached under another path of the same vault
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(a.rootPath, link); err != nil {
+		t.Skip(err)
+	}
+	config, _ := a.LoadConfig(a.rootPath)
+	config.PrivacyMode = false
+	a.HasSecurity(link)
+	if err := a.SaveConfig(config, a.rootPath); err != nil {
+		t.Fatal(err)
+	}
+	if a.HasSecurity(link) {
+		t.Fatal("every path of the vault must be refreshed")
+	}
+}
+
+func TestHasSecurityKeyedByResolvedRoot(t *testing.T) {
+	a := newTestVault(t)
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(a.rootPath, link); err != nil {
+		t.Skip(err)
+	}
+	a.HasSecurity(a.rootPath)
+	a.HasSecurity(link)
+	a.HasSecurity(a.rootPath + string(filepath.Separator))
+	if len(a.security.byRoot) != 1 {
+		t.Fatalf("expected one cached vault, got %v", a.security.byRoot)
+	}
+	if a.HasSecurity(t.TempDir()) || len(a.security.byRoot) != 1 {
+		t.Fatal("a path outside the vault must not be cached")
+	}
+}
+
+func TestHasSecurityConfigError(t *testing.T) {
+	a := newTestVault(t)
+	if response := a.SetupPassword("testpassword", a.rootPath); response != "ok" {
+		t.Fatal(response)
+	}
+	configPath := a.getConfigPath(a.rootPath)
+	config, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// eg: tape.json read while being written
+	a.security.byRoot = nil
+	if err := os.WriteFile(configPath, config[:len(config)/2], 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.securityOf(a.rootPath); err == nil {
+		t.Fatal("expected an error for a partial config")
+	}
+	notePath := filepath.Join(a.rootPath, "note.mde")
+	if err := a.WriteContentInFile(notePath, "secret"); err == nil {
+		t.Fatal("a note must not be written while the config can't be read")
+	}
+	if a.IsFileExists(notePath) {
+		t.Fatal("the note was written in plain text")
+	}
+
+	// the error isn't cached, the config is read again once it is whole
+	if err := os.WriteFile(configPath, config, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if !a.HasSecurity(a.rootPath) {
+		t.Fatal("expected the vault to be secured once the config is readable")
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	goruntime "runtime"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// searchWorkers is the number of notes read and scanned at the same time
+var searchWorkers = min(goruntime.NumCPU(), 8)
+
+const (
+	searchTimeBudget        = 5 * time.Second // past it the results found so far are returned
+	searchMaxMatchesPerFile = 100
+	searchSnippetContext    = 60 // runes kept on each side of a match
+)
+
+type SearchOptions struct {
+	Regex         bool   `json:"regex"`
+	CaseSensitive bool   `json:"caseSensitive"`
+	WholeWord     bool   `json:"wholeWord"`
+	Offset        int    `json:"offset"` // pagination of the sorted results
+	Limit         int    `json:"limit"`  // 0 means searchDefaultLimit
+	Scope         string `json:"scope"`  // only matches in "code", "heading", "link", "text" or "frontmatter", anywhere when empty
+}
+
+// searchMatcher finds a query in names and content according to the search options
+type searchMatcher struct {
+	query    string
+	options  SearchOptions
+	pattern  *regexp.Regexp
+	deadline time.Time
+}
+
+/**
+ * --- Search options
+ */
+// newSearchMatcher compiles the query, an invalid regex is reported as "invalid_regex: <reason>"
+// note: Go regexps run in linear time, there is no catastrophic backtracking to guard against
+func newSearchMatcher(query string, options SearchOptions) (*searchMatcher, error) {
+	if options.Scope != "" && !isMarkdownElement(options.Scope) {
+		return nil, fmt.Errorf("invalid_scope")
+	}
+
+	expr := query
+	if !options.Regex {
+		expr = regexp.QuoteMeta(query)
+	}
+	if !options.CaseSensitive {
+		expr = "(?i)" + expr
+	}
+
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		var syntaxErr *syntax.Error
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("invalid_regex: %s", syntaxErr.Code)
+		}
+		return nil, fmt.Errorf("invalid_regex")
+	}
+
+	return &searchMatcher{
+		query:    query,
+		options:  options,
+		pattern:  pattern,
+		deadline: time.Now().Add(searchTimeBudget),
+	}, nil
+}
+
+// isPlain reports if no matching option is set, names are then matched fuzzily
+func (m *searchMatcher) isPlain() bool {
+	return !m.options.Regex && !m.options.CaseSensitive && !m.options.WholeWord
+}
+
+// expired reports if the time budget of the search is spent
+func (m *searchMatcher) expired() bool {
+	return time.Now().After(m.deadline)
+}
+
+// matchName scores a file or folder name against the query, fuzzily without options
+// a query holding a path separator is matched against the path inside the vault, eg: "proj/plan"
+func (m *searchMatcher) matchName(name string, relPath func() string) (int, bool) {
+	if m.options.Scope != "" {
+		return 0, false // a scope selects parts of the content
+	}
+	if !m.isPlain() {
+		start, _ := m.find(name)
+		return 0, start != -1
+	}
+	if strings.ContainsAny(m.query, `/\`) {
+		return fuzzyScore(strings.ReplaceAll(m.query, `\`, "/"), relPath())
+	}
+	return fuzzyScore(m.query, name)
+}
+
+// find returns the byte range of the first match in text, -1 when there is none
+func (m *searchMatcher) find(text string) (int, int) {
+	matches := m.findAll(text, 1)
+	if len(matches) == 0 {
+		return -1, -1
+	}
+	return matches[0][0], matches[0][1]
+}
+
+// findAll returns at most limit matches in text, a negative limit means all of them, eg: for a replace
+// each match holds the byte range of the match followed by the ranges of the regex groups
+// with a scope, the matches outside of it are left out
+// with a limit, the regexp looks for limit matches first then for more while some are left out,
+// until the time budget is spent: a broad regex like "." doesn't collect every match of a large note
+// note: the text isn't sliced to go on from the last match, "^" and "\b" would match at the cut
+func (m *searchMatcher) findAll(text string, limit int) [][]int {
+	if limit < 0 {
+		return m.keepMatches(text, m.pattern.FindAllStringSubmatchIndex(text, -1), -1)
+	}
+	for n := limit; n > 0; n *= 4 {
+		found := m.pattern.FindAllStringSubmatchIndex(text, n)
+		matches := m.keepMatches(text, found, limit)
+		if len(matches) == limit || len(found) < n || m.expired() {
+			return matches
+		}
+	}
+	return nil
+}
+
+// keepMatches returns at most limit of the found matches which aren't empty, are whole words with the
+// option and are in the scope if any
+func (m *searchMatcher) keepMatches(text string, found [][]int, limit int) [][]int {
+	var matches [][]int
+	var regions []mdRegion
+	for _, match := range found {
+		if len(matches) == limit {
+			break
+		}
+		if match[0] == match[1] {
+			continue // empty matches, eg: "a*", highlight nothing
+		}
+		if m.options.WholeWord && !isWordBoundary(text, match[0], match[1]) {
+			continue
+		}
+		if m.options.Scope != "" {
+			if regions == nil {
+				regions = markdownRegions(text)
+			}
+			if regionAt(regions, match[0]) != m.options.Scope {
+				continue
+			}
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// findMatches returns the matches in a note with their position, the markdown element they are in
+// and a snippet to highlight them. With a scope, matches outside of it are left out
+func (m *searchMatcher) findMatches(content string) []SearchMatch {
+	found := m.findAll(content, searchMaxMatchesPerFile)
+	if len(found) == 0 {
+		return nil
+	}
+	regions := markdownRegions(content)
+
+	var matches []SearchMatch
+	line, scanned := 1, 0
+
+	for _, match := range found {
+		start, end := match[0], match[1]
+		element := regionAt(regions, start)
+
+		// lines are counted from the previous match only
+		line += strings.Count(content[scanned:start], "\n")
+		scanned = start
+		lineStart := strings.LastIndexByte(content[:start], '\n') + 1
+
+		lineEnd := len(content)
+		if next := strings.IndexByte(content[start:], '\n'); next != -1 {
+			lineEnd = start + next
+		}
+		lineEnd = max(len(strings.TrimSuffix(content[:lineEnd], "\r")), start)
+
+		snippet, snippetStart := matchSnippet(content, lineStart, lineEnd, start, min(end, lineEnd))
+		matches = append(matches, SearchMatch{
+			Line:      line,
+			Column:    utf8.RuneCountInString(content[lineStart:start]) + 1,
+			Offset:    start,
+			Length:    end - start,
+			Snippet:   snippet,
+			Start:     start - snippetStart,
+			End:       min(end, lineEnd) - snippetStart,
+			RuneStart: utf8.RuneCountInString(content[snippetStart:start]),
+			RuneEnd:   utf8.RuneCountInString(content[snippetStart:min(end, lineEnd)]),
+			Element:   element,
+		})
+	}
+
+	return matches
+}
+
+// matchSnippet returns the part of a line around a match and the offset it starts at in the content
+// the cut is made on rune boundaries, a highlight spreading over several lines ends with the line
+func matchSnippet(content string, lineStart, lineEnd, start, end int) (string, int) {
+	from := start
+	for i := 0; i < searchSnippetContext && from > lineStart; i++ {
+		_, size := utf8.DecodeLastRuneInString(content[lineStart:from])
+		from -= size
+	}
+	to := end
+	for i := 0; i < searchSnippetContext && to < lineEnd; i++ {
+		_, size := utf8.DecodeRuneInString(content[to:lineEnd])
+		to += size
+	}
+	return content[from:to], from
+}
+
+// useIndex reports if the index can narrow the notes to read: regexps may match anything
+func (m *searchMatcher) useIndex() bool {
+	return !m.options.Regex && len(tokenize(m.query)) > 0
+}
+
+// isWordBoundary reports if text[start:end] is not surrounded by letters, digits or underscores
+func isWordBoundary(text string, start, end int) bool {
+	isWordRune := func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	if before, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWordRune(before) {
+		return false
+	}
+	if after, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWordRune(after) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchMatcherOptions(t *testing.T) {
+	tests := []struct {
+		query    string
+		options  SearchOptions
+		text     string
+		expected string
+		at       int
+	}{
+		{"todo", SearchOptions{}, "A TODO item", "TODO", 2},
+		{"todo", SearchOptions{CaseSensitive: true}, "A TODO item, todo", "todo", 13},
+		{`TODO\(\w+\)`, SearchOptions{Regex: true}, "see todo(jane) later", "todo(jane)", 4},
+		{"cat", SearchOptions{WholeWord: true}, "concatenate the cat", "cat", 16},
+		{"été", SearchOptions{WholeWord: true}, "étés, été", "été", 8},
+		{"a.b", SearchOptions{}, "axb a.b", "a.b", 4},
+		{"ÉTÉ", SearchOptions{}, "un été", "été", 3},
+	}
+
+	for _, tt := range tests {
+		matcher, err := newSearchMatcher(tt.query, tt.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		start, end := matcher.find(tt.text)
+		if start != tt.at || tt.text[start:end] != tt.expected {
+			t.Fatalf("%q %+v: expected %q at %d in %q, got %d..%d", tt.query, tt.options, tt.expected, tt.at, tt.text, start, end)
+		}
+	}
+
+	matcher, _ := newSearchMatcher("cat", SearchOptions{WholeWord: true})
+	if start, _ := matcher.find("concatenate"); start != -1 {
+		t.Fatal("a part of a word must not match")
+	}
+}
+
+func TestSearchMatcherInvalidRegex(t *testing.T) {
+	_, err := newSearchMatcher(`TODO(\w+`, SearchOptions{Regex: true})
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid_regex: ") {
+		t.Fatalf("expected an invalid_regex error, got %v", err)
+	}
+
+	// the same text is a plain query without the regex option
+	if _, err := newSearchMatcher(`TODO(\w+`, SearchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearchFilesWithOptions(t *testing.T) {
+	a := newTestVault(t)
+	a.WriteContentInFile(filepath.Join(a.rootPath, "a.md"), "call ParseQuery here")
+	a.WriteContentInFile(filepath.Join(a.rootPath, "b.md"), "no parsequery there")
+
+	results, err := a.SearchFiles(a.rootPath, "ParseQuery", SearchOptions{CaseSensitive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Name != "a.md" || results[0].MatchText != "ParseQuery" {
+		t.Fatalf("unexpected case-sensitive results %+v", results)
+	}
+
+	results, _ = a.SearchFiles(a.rootPath, `[Pp]arse\w+ (here|there)`, SearchOptions{Regex: true})
+	if len(results) != 2 || results[1].MatchText != "parsequery there" {
+		t.Fatalf("unexpected regex results %+v", results)
+	}
+	if len(results[1].Matches) != 1 || results[1].Matches[0].Column != 4 {
+		t.Fatalf("expected the match position, got %+v", results[1].Matches)
+	}
+
+	if _, err := a.SearchFiles(a.rootPath, "(", SearchOptions{Regex: true}); err == nil {
+		t.Fatal("expected an error for an invalid regex")
+	}
+}
+
+func TestSearchMatcherExpires(t *testing.T) {
+	a := newTestVault(t)
+	a.WriteContentInFile(filepath.Join(a.rootPath, "a.md"), "content")
+
+	matcher, _ := newSearchMatcher("content", SearchOptions{})
+	matcher.deadline = time.Now().Add(-time.Second)
+	truncated, _ := a.scanSearch(context.Background(), a.rootPath, matcher, func(result *SearchResult, _, _ int) bool {
+		if result != nil && result.MatchType == "content" {
+			t.Fatal("an expired search must not scan the content anymore")
+		}
+		return true
+	})
+	if !truncated {
+		t.Fatal("an expired search must be reported as truncated")
+	}
+
+	matcher, _ = newSearchMatcher("content", SearchOptions{})
+	if truncated, _ := a.scanSearch(context.Background(), a.rootPath, matcher, func(*SearchResult, int, int) bool { return true }); truncated {
+		t.Fatal("a complete search must not be reported as truncated")
+	}
+}
+
+func TestFindMatches(t *testing.T) {
+	matcher, _ := newSearchMatcher("todo", SearchOptions{})
+	content := "# Plan\r\nécrire le TODO puis todo\n\nfin todo"
+
+	matches := matcher.findMatches(content)
+	if len(matches) != 3 {
+		t.Fatalf("expected every match, got %+v", matches)
+	}
+
+	expected := []struct{ line, column int }{{2, 11}, {2, 21}, {4, 5}}
+	for i, match := range matches {
+		if match.Line != expected[i].line || match.Column != expected[i].column {
+			t.Fatalf("match %d: expected %d:%d, got %d:%d", i, expected[i].line, expected[i].column, match.Line, match.Column)
+		}
+		if content[match.Offset:match.Offset+match.Length] != match.Snippet[match.Start:match.End] {
+			t.Fatalf("match %d: the highlight doesn't point to the match in %q", i, match.Snippet)
+		}
+		if string([]rune(match.Snippet)[match.RuneStart:match.RuneEnd]) != match.Snippet[match.Start:match.End] {
+			t.Fatalf("match %d: rune offsets don't point to the match", i)
+		}
+	}
+	if matches[0].Snippet != "écrire le TODO puis todo" {
+		t.Fatalf("the snippet must be the line without its ending, got %q", matches[0].Snippet)
+	}
+}
+
+func TestFindMatchesCutsLongLines(t *testing.T) {
+	matcher, _ := newSearchMatcher("needle", SearchOptions{})
+	content := strings.Repeat("é", 200) + "needle" + strings.Repeat("à", 200)
+
+	matches := matcher.findMatches(content)
+	if len(matches) != 1 {
+		t.Fatal("expected a match")
+	}
+	snippet := []rune(matches[0].Snippet)
+	if len(snippet) != 2*searchSnippetContext+len("needle") || matches[0].RuneStart != searchSnippetContext {
+		t.Fatalf("unexpected snippet of %d runes starting the highlight at %d", len(snippet), matches[0].RuneStart)
+	}
+
+	many := strings.Repeat("needle ", 2*searchMaxMatchesPerFile)
+	if len(matcher.findMatches(many)) != searchMaxMatchesPerFile {
+		t.Fatal("matches must be limited per file")
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	for _, tt := range []struct{ pattern, text string }{
+		{"été", "Résumé de l'Été.md"},
+		{"ete", "Résumé de l'Ete.md"},
+		{"fb", "FooBar.md"},
+		{"", "anything"},
+	} {
+		if _, ok := fuzzyScore(tt.pattern, tt.text); !ok {
+			t.Fatalf("expected %q to match %q", tt.pattern, tt.text)
+		}
+	}
+	for _, tt := range []struct{ pattern, text string }{
+		{"été", "ete.md"},
+		{"ba", "ab.md"},
+		{"long pattern", "short"},
+	} {
+		if _, ok := fuzzyScore(tt.pattern, tt.text); ok {
+			t.Fatalf("expected %q not to match %q", tt.pattern, tt.text)
+		}
+	}
+
+	better := func(pattern, best, worse string) {
+		t.Helper()
+		bestScore, _ := fuzzyScore(pattern, best)
+		worseScore, _ := fuzzyScore(pattern, worse)
+		if bestScore <= worseScore {
+			t.Fatalf("%q: expected %q (%d) to score over %q (%d)", pattern, best, bestScore, worse, worseScore)
+		}
+	}
+	better("note", "notes.md", "n-o-t-e.md")       // consecutive runes
+	better("fb", "FooBar.md", "afxbx.md")          // camel case word starts
+	better("mn", "my-note.md", "amen.md")          // word starts after a separator
+	better("pp", "projects/plan.md", "app/x.md")   // path segment starts
+	better("plan", "x/plan.md", "x/the planets/a") // the best alignment wins
+}
+
+func TestSearchFilesOrdersByScore(t *testing.T) {
+	a := newTestVault(t)
+	os.Mkdir(filepath.Join(a.rootPath, "projects"), 0700)
+	for _, rel := range []string{"n-o-t-e.md", "zz_notes.md", "projects/plan.md", "plan.md"} {
+		a.WriteContentInFile(filepath.Join(a.rootPath, rel), "")
+	}
+
+	results, _ := a.SearchFiles(a.rootPath, "note", SearchOptions{})
+	if len(results) != 2 || results[0].Name != "zz_notes.md" || results[0].Score <= results[1].Score {
+		t.Fatalf("expected the best score first, got %+v", results)
+	}
+
+	results, _ = a.SearchFiles(a.rootPath, "proj/plan", SearchOptions{})
+	if len(results) != 1 || results[0].Path != filepath.Join(a.rootPath, "projects", "plan.md") {
+		t.Fatalf("a query with a separator must match the path, got %+v", results)
+	}
+}
+
+func TestSearchFilesScope(t *testing.T) {
+	a := newTestVault(t)
+	a.WriteContentInFile(filepath.Join(a.rootPath, "deploy.md"), "# Deploy\n\nRun deploy with:\n\n```sh\nmake deploy\n```\n")
+
+	results, err := a.SearchFiles(a.rootPath, "deploy", SearchOptions{Scope: "code"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || len(results[0].Matches) != 1 || results[0].Matches[0].Line != 6 {
+		t.Fatalf("expected only the match in the code block, got %+v", results)
+	}
+
+	results, _ = a.SearchFiles(a.rootPath, "deploy", SearchOptions{})
+	var elements []string
+	for _, result := range results {
+		for _, match := range result.Matches {
+			elements = append(elements, match.Element)
+		}
+	}
+	if strings.Join(elements, ",") != "heading,text,code" {
+		t.Fatalf("expected every match to report its element, got %v", elements)
+	}
+
+	if _, err := a.SearchFiles(a.rootPath, "deploy", SearchOptions{Scope: "table"}); err == nil {
+		t.Fatal("expected an error for an unknown scope")
+	}
+}
+
+func TestFindAllBudget(t *testing.T) {
+	// only the last "a" is a whole word, the matches before it are looked through in growing batches
+	text := strings.Repeat("ab ", 1000) + "a"
+	matcher, _ := newSearchMatcher("a", SearchOptions{WholeWord: true})
+	if matches := matcher.findAll(text, 1); len(matches) != 1 || matches[0][0] != len(text)-1 {
+		t.Fatalf("expected the last match, got %v", matches)
+	}
+
+	matcher.deadline = time.Now().Add(-time.Second)
+	if matches := matcher.findAll(text, 1); len(matches) != 0 {
+		t.Fatal("an expired search must stop looking for more matches")
+	}
+	if matches := matcher.findAll(text, -1); len(matches) != 1 {
+		t.Fatal("a replace needs every match, whatever the time budget")
+	}
+}
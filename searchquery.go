@@ -0,0 +1,499 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// queryNode is a node of a parsed search query
+// ops: "and", "or", "not" with children, "term", "phrase", "path", "tag", "modified" with a lowercased value
+type queryNode struct {
+	op       string
+	children []*queryNode
+	value    string
+	cmp      string    // modified: "", "<", "<=", ">" or ">="
+	date     time.Time // modified: start of the day, local time
+}
+
+type queryToken struct {
+	kind string // "(", ")", "word" or "phrase"
+	text string
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+// queryTarget is a note or folder a query is evaluated against, the content is read on first use
+type queryTarget struct {
+	a            *App
+	index        *searchIndex // nil without index
+	path         string
+	doc          *indexedDoc
+	relLower     string // decrypted, slash separated
+	nameLower    string
+	content      string
+	contentLower string
+	loaded       bool
+}
+
+/**
+ * --- Search query
+ */
+// lexQuery splits a query in parentheses, "quoted phrases" and words
+// a quoted value right after a field stays in its word, eg: path:"my notes/"
+func lexQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			continue
+		case r == '(' || r == ')':
+			tokens = append(tokens, queryToken{kind: string(r)})
+		case r == '"':
+			end := slices.Index(runes[i+1:], '"')
+			if end == -1 {
+				return nil, fmt.Errorf("invalid_query")
+			}
+			tokens = append(tokens, queryToken{kind: "phrase", text: string(runes[i+1 : i+1+end])})
+			i += end + 1
+		default:
+			var word strings.Builder
+			for ; i < len(runes) && !strings.ContainsRune(" \t\n\r()", runes[i]); i++ {
+				if runes[i] != '"' {
+					word.WriteRune(runes[i])
+					continue
+				}
+				end := slices.Index(runes[i+1:], '"')
+				if end == -1 {
+					return nil, fmt.Errorf("invalid_query")
+				}
+				word.WriteString(string(runes[i+1 : i+1+end]))
+				i += end + 1
+			}
+			i--
+			tokens = append(tokens, queryToken{kind: "word", text: word.String()})
+		}
+	}
+
+	return tokens, nil
+}
+
+// parseQuery parses a search query, eg: "exact phrase" foo AND NOT bar path:projects/ tag:todo modified:>2026-09-01
+// words next to each other are ANDed, AND binds tighter than OR and parentheses group
+func parseQuery(query string) (*queryNode, error) {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("invalid_query")
+	}
+
+	parser := &queryParser{tokens: tokens}
+	node, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.pos != len(tokens) {
+		return nil, fmt.Errorf("invalid_query") // eg: a stray ")"
+	}
+	return node, nil
+}
+
+// isOperator reports if the next token is the given operator, operators are uppercase only
+func (p *queryParser) isOperator(operator string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == "word" && p.tokens[p.pos].text == operator
+}
+
+func (p *queryParser) parseOr() (*queryNode, error) {
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator("OR") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if node.op != "or" {
+			node = &queryNode{op: "or", children: []*queryNode{node}}
+		}
+		node.children = append(node.children, right)
+	}
+	return node, nil
+}
+
+func (p *queryParser) parseAnd() (*queryNode, error) {
+	node := &queryNode{op: "and"}
+
+	for p.pos < len(p.tokens) && p.tokens[p.pos].kind != ")" && !p.isOperator("OR") {
+		if p.isOperator("AND") {
+			if len(node.children) == 0 {
+				return nil, fmt.Errorf("invalid_query")
+			}
+			p.pos++
+		}
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, child)
+	}
+
+	switch len(node.children) {
+	case 0:
+		return nil, fmt.Errorf("invalid_query") // eg: "foo OR", "()"
+	case 1:
+		return node.children[0], nil
+	default:
+		return node, nil
+	}
+}
+
+func (p *queryParser) parseUnary() (*queryNode, error) {
+	if p.isOperator("NOT") {
+		p.pos++
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &queryNode{op: "not", children: []*queryNode{child}}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (*queryNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("invalid_query")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+
+	switch token.kind {
+	case "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != ")" {
+			return nil, fmt.Errorf("invalid_query")
+		}
+		p.pos++
+		return node, nil
+	case "phrase":
+		return &queryNode{op: "phrase", value: strings.ToLower(token.text)}, nil
+	case "word":
+		if token.text == "AND" || token.text == "OR" {
+			return nil, fmt.Errorf("invalid_query")
+		}
+		field, value, found := strings.Cut(token.text, ":")
+		if found && value != "" {
+			switch strings.ToLower(field) {
+			case "path":
+				return &queryNode{op: "path", value: strings.ToLower(value)}, nil
+			case "tag":
+				return &queryNode{op: "tag", value: strings.ToLower(strings.TrimPrefix(value, "#"))}, nil
+			case "modified":
+				return parseModified(value)
+			}
+		}
+		return &queryNode{op: "term", value: strings.ToLower(token.text)}, nil
+	}
+	return nil, fmt.Errorf("invalid_query")
+}
+
+// parseModified parses the value of a modified: filter, a date optionally prefixed by a comparison
+func parseModified(value string) (*queryNode, error) {
+	node := &queryNode{op: "modified"}
+	for _, cmp := range []string{"<=", ">=", "<", ">"} {
+		if strings.HasPrefix(value, cmp) {
+			node.cmp = cmp
+			value = value[len(cmp):]
+			break
+		}
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid_query")
+	}
+	node.date = date
+	return node, nil
+}
+
+// readContent returns the content of the target and its lowercased version, folders have none
+func (t *queryTarget) readContent() (string, string) {
+	if !t.loaded {
+		t.loaded = true
+		if !t.doc.IsDir {
+			t.content, _ = t.a.readIndexedNote(t.index, t.path)
+			t.contentLower = strings.ToLower(t.content)
+		}
+	}
+	return t.content, t.contentLower
+}
+
+// matchQuery evaluates a parsed query against a note or folder
+func matchQuery(node *queryNode, target *queryTarget) bool {
+	switch node.op {
+	case "and":
+		for _, child := range node.children {
+			if !matchQuery(child, target) {
+				return false
+			}
+		}
+		return true
+	case "or":
+		for _, child := range node.children {
+			if matchQuery(child, target) {
+				return true
+			}
+		}
+		return false
+	case "not":
+		return !matchQuery(node.children[0], target)
+	case "term", "phrase":
+		if strings.Contains(target.nameLower, node.value) {
+			return true
+		}
+		_, contentLower := target.readContent()
+		return strings.Contains(contentLower, node.value)
+	case "path":
+		return strings.Contains(target.relLower, node.value)
+	case "tag":
+		content, _ := target.readContent()
+		return slices.Contains(noteTags(content), node.value)
+	case "modified":
+		if target.doc.IsDir {
+			return false // the time of a folder changes with its children only
+		}
+		modified := time.Unix(0, target.doc.ModTime)
+		nextDay := node.date.AddDate(0, 0, 1)
+		switch node.cmp {
+		case "<":
+			return modified.Before(node.date)
+		case "<=":
+			return modified.Before(nextDay)
+		case ">":
+			return !modified.Before(nextDay)
+		case ">=":
+			return !modified.Before(node.date)
+		default:
+			return !modified.Before(node.date) && modified.Before(nextDay)
+		}
+	}
+	return false
+}
+
+// positiveTerms returns the terms and phrases a match is looked for, the negated ones are left out
+func positiveTerms(node *queryNode) []string {
+	switch node.op {
+	case "term", "phrase":
+		return []string{node.value}
+	case "and", "or":
+		var terms []string
+		for _, child := range node.children {
+			terms = append(terms, positiveTerms(child)...)
+		}
+		return terms
+	}
+	return nil
+}
+
+// candidates returns the paths that may match a query, nil when every document may match
+// terms are looked up as substrings of the words and names, as matchQuery matches them
+func (index *searchIndex) candidates(node *queryNode) map[string]bool {
+	switch node.op {
+	case "term", "phrase":
+		if len(tokenize(node.value)) == 0 {
+			return nil
+		}
+		paths := make(map[string]bool)
+		for _, doc := range index.lookup(node.value) {
+			paths[doc.Path] = true
+		}
+		for _, doc := range index.allDocs() {
+			if strings.Contains(strings.ToLower(doc.Name), node.value) {
+				paths[doc.Path] = true
+			}
+		}
+		return paths
+	case "and":
+		var paths map[string]bool
+		for _, child := range node.children {
+			childPaths := index.candidates(child)
+			if childPaths == nil {
+				continue
+			}
+			if paths == nil {
+				paths = childPaths
+				continue
+			}
+			for path := range paths {
+				if !childPaths[path] {
+					delete(paths, path)
+				}
+			}
+		}
+		return paths
+	case "or":
+		paths := make(map[string]bool)
+		for _, child := range node.children {
+			childPaths := index.candidates(child)
+			if childPaths == nil {
+				return nil
+			}
+			for path := range childPaths {
+				paths[path] = true
+			}
+		}
+		return paths
+	}
+	return nil
+}
+
+// listVaultDocs lists the notes and folders of the vault, used while the index is not available
+func (a *App) listVaultDocs(rootPath string) []*indexedDoc {
+	var docs []*indexedDoc
+
+	filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // skip files with errors
+		}
+		rel, err := filepath.Rel(rootPath, path)
+		if err != nil || rel == "." {
+			return nil
+		}
+		if !isIndexable(rel) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() && !isMDorMDE(info.Name()) {
+			return nil
+		}
+
+		name := info.Name()
+		if a.HasSecurity(rootPath) {
+			name = a.GetDecryptedFileName(path)
+		}
+		docs = append(docs, &indexedDoc{
+			Path:    rel,
+			Name:    name,
+			IsDir:   info.IsDir(),
+			ModTime: info.ModTime().UnixNano(),
+			Size:    info.Size(),
+		})
+		return nil
+	})
+
+	return docs
+}
+
+// SearchAdvanced searches notes and folders with the query syntax:
+// - words and "exact phrases" are looked for in names and content
+// - AND (implicit between words), OR, NOT and parentheses
+// - path:projects/ matches the path inside the vault
+// - tag:todo matches notes with #todo
+// - modified:2026-09-01, modified:>2026-09-01 (also <, <=, >=) matches the modification day
+func (a *App) SearchAdvanced(rootPath string, query string) ([]SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return []SearchResult{}, nil
+	}
+
+	rootPath, err := a.resolveVaultPath(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []*indexedDoc
+	index := a.searchIndexFor(rootPath)
+	if index != nil {
+		paths := index.candidates(node)
+		for _, doc := range index.allDocs() {
+			if paths == nil || paths[doc.Path] {
+				docs = append(docs, doc)
+			}
+		}
+	} else {
+		docs = a.listVaultDocs(rootPath)
+	}
+
+	terms := positiveTerms(node)
+	results := []SearchResult{}
+	for _, doc := range docs {
+		rel := doc.Path
+		if a.HasSecurity(rootPath) {
+			rel = a.GetDecryptedFullPath(rel, 0)
+		}
+		target := &queryTarget{
+			a:         a,
+			index:     index,
+			path:      filepath.Join(rootPath, doc.Path),
+			doc:       doc,
+			relLower:  strings.ToLower(filepath.ToSlash(rel)),
+			nameLower: strings.ToLower(doc.Name),
+		}
+		if !matchQuery(node, target) {
+			continue
+		}
+		results = append(results, queryResult(target, terms))
+	}
+
+	sortSearchResults(results)
+	return pageSearchResults(results, SearchOptions{}).Results, nil
+}
+
+// queryResult builds the result of a matching target, showing where the first positive term is found
+func queryResult(target *queryTarget, terms []string) SearchResult {
+	result := SearchResult{
+		Path:      target.path,
+		Name:      target.doc.Name,
+		IsDir:     target.doc.IsDir,
+		MatchType: "filename",
+		MatchText: target.doc.Name,
+		Meta:      target.doc.Meta,
+	}
+	if target.doc.IsDir {
+		result.MatchType = "foldername"
+	}
+
+	for _, term := range terms {
+		if strings.Contains(target.nameLower, term) {
+			return result
+		}
+	}
+	// offsets are searched in the content itself, lowercasing may change its length
+	content, _ := target.readContent()
+	for _, term := range terms {
+		matcher, err := newSearchMatcher(term, SearchOptions{})
+		if err != nil {
+			continue
+		}
+		if matches := matcher.findMatches(content); len(matches) > 0 {
+			result.MatchType = "content"
+			result.MatchText, result.ContextText = getContextAroundMatch(content, matches[0].Offset, matches[0].Offset+matches[0].Length)
+			result.Matches = matches
+			result.Score = len(matches)
+			return result
+		}
+	}
+	return result
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	node, err := parseQuery(`"Exact Phrase" foo OR bar AND NOT (baz OR path:"my notes/")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.op != "or" || len(node.children) != 2 {
+		t.Fatalf("expected OR at the top, got %+v", node)
+	}
+	left, right := node.children[0], node.children[1]
+	if left.op != "and" || left.children[0].op != "phrase" || left.children[0].value != "exact phrase" {
+		t.Fatalf("unexpected left side %+v", left)
+	}
+	if right.op != "and" || right.children[1].op != "not" || right.children[1].children[0].children[1].value != "my notes/" {
+		t.Fatalf("unexpected right side %+v", right)
+	}
+
+	for _, query := range []string{`"unclosed`, "(foo", "foo)", "foo OR", "AND foo", "modified:yesterday", "NOT"} {
+		if _, err := parseQuery(query); err == nil {
+			t.Fatalf("expected %q to be rejected", query)
+		}
+	}
+
+	if node, _ := parseQuery("http://example.com"); node.op != "term" {
+		t.Fatal("an unknown field must be searched as a word")
+	}
+}
+
+func TestSearchAdvanced(t *testing.T) {
+	a := newTestVault(t)
+	os.Mkdir(filepath.Join(a.rootPath, "projects"), 0700)
+	write := func(rel, content string, modified time.Time) {
+		path := filepath.Join(a.rootPath, rel)
+		a.WriteContentInFile(path, content)
+		os.Chtimes(path, modified, modified)
+	}
+	write("projects/launch.md", "The launch plan #todo", time.Date(2026, 9, 15, 10, 0, 0, 0, time.Local))
+	write("projects/retro.md", "What went wrong with the launch", time.Date(2026, 8, 20, 10, 0, 0, 0, time.Local))
+	write("ideas.md", "A plan for the garden #someday", time.Date(2026, 9, 1, 10, 0, 0, 0, time.Local))
+
+	search := func(query string) []string {
+		t.Helper()
+		results, err := a.SearchAdvanced(a.rootPath, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, result := range results {
+			names = append(names, result.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{`"launch plan"`, []string{"launch.md"}},
+		{"plan AND NOT launch", []string{"ideas.md"}},
+		{"garden OR wrong", []string{"ideas.md", "retro.md"}},
+		{"path:projects/ launch", []string{"launch.md", "retro.md"}},
+		{"tag:todo", []string{"launch.md"}},
+		{"modified:>2026-09-01", []string{"launch.md"}},
+		{"modified:>=2026-09-01", []string{"ideas.md", "launch.md"}},
+		{"modified:2026-08-20", []string{"retro.md"}},
+		{"modified:<2026-09-01 path:projects", []string{"retro.md"}},
+	}
+	for _, tt := range tests {
+		names := search(tt.query)
+		if len(names) != len(tt.expected) {
+			t.Fatalf("%s: expected %v, got %v", tt.query, tt.expected, names)
+		}
+		for i := range names {
+			if names[i] != tt.expected[i] {
+				t.Fatalf("%s: expected %v, got %v", tt.query, tt.expected, names)
+			}
+		}
+	}
+
+	results, _ := a.SearchAdvanced(a.rootPath, "launch plan")
+	if results[0].MatchType != "filename" {
+		t.Fatalf("a name match must be reported as such, got %+v", results[0])
+	}
+	results, _ = a.SearchAdvanced(a.rootPath, "garden")
+	if results[0].MatchType != "content" || results[0].MatchText != "garden" {
+		t.Fatalf("unexpected content match %+v", results[0])
+	}
+}
+
+func TestSearchAdvancedWithoutIndex(t *testing.T) {
+	a := newTestVault(t)
+	if response := a.SetupPassword("testpassword", a.rootPath); response != "ok" {
+		t.Fatal(response)
+	}
+	a.WriteContentInFile(filepath.Join(a.rootPath, "MDE1note.mde"), "secret #todo")
+	a.masterkey = nil
+
+	results, err := a.SearchAdvanced(a.rootPath, "tag:todo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Fatal("a locked vault must not reveal its content")
+	}
+}
+
+func TestSearchAdvancedSameWithoutIndex(t *testing.T) {
+	a := newTestVault(t)
+	folder := filepath.Join(a.rootPath, "notes")
+	os.Mkdir(folder, 0700)
+	a.WriteContentInFile(filepath.Join(folder, "greeting.md"), "Hello world")
+	a.WriteContentInFile(filepath.Join(folder, "market.md"), "go to the market")
+	a.WriteContentInFile(filepath.Join(folder, "yellow.md"), "a yellow car")
+
+	paths := func(rootPath, query string) []string {
+		t.Helper()
+		results, err := a.SearchAdvanced(rootPath, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths := []string{}
+		for _, result := range results {
+			if result.Path != folder {
+				paths = append(paths, result.Path)
+			}
+		}
+		sort.Strings(paths)
+		return paths
+	}
+
+	// the vault is searched through the index, a folder of it by a walk
+	for _, query := range []string{"ell", `"lo wo"`, "ell AND NOT car", "arke OR ellow", "reet"} {
+		indexed, walked := paths(a.rootPath, query), paths(folder, query)
+		if len(indexed) == 0 || !reflect.DeepEqual(indexed, walked) {
+			t.Fatalf("%s: expected %v with the index, got %v", query, walked, indexed)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	searchDefaultLimit  = 60
+	searchBatchSize     = 50
+	searchBatchInterval = 100 * time.Millisecond
+)
+
+type SearchPage struct {
+	Results   []SearchResult `json:"results"`
+	Offset    int            `json:"offset"`
+	Total     int            `json:"total"`
+	HasMore   bool           `json:"hasMore"`
+	Truncated bool           `json:"truncated"` // the time budget was spent before every note was scanned
+}
+
+// SearchBatch is the payload of the "search:results" and "search:done" events of a streamed search
+type SearchBatch struct {
+	ID        int64          `json:"id"`
+	Results   []SearchResult `json:"results"`   // new results since the previous batch, in scan order
+	Scanned   int            `json:"scanned"`   // names and notes scanned so far
+	Total     int            `json:"total"`     // names and notes to scan
+	Found     int            `json:"found"`     // results sent so far
+	Estimate  int            `json:"estimate"`  // expected number of results once the scan is over
+	Truncated bool           `json:"truncated"` // the time budget was spent before every note was scanned, set on "search:done"
+	Done      bool           `json:"done"`
+}
+
+/**
+ * --- Search pagination and streaming
+ */
+// pageSearchResults selects the page of sorted results asked by the options
+func pageSearchResults(results []SearchResult, options SearchOptions) SearchPage {
+	limit := options.Limit
+	if limit <= 0 {
+		limit = searchDefaultLimit
+	}
+	start := min(max(options.Offset, 0), len(results))
+	end := min(start+limit, len(results))
+
+	return SearchPage{
+		Results: results[start:end],
+		Offset:  start,
+		Total:   len(results),
+		HasMore: end < len(results),
+	}
+}
+
+// estimateResults projects the number of results of a scan from the part already scanned
+func estimateResults(found, scanned, total int) int {
+	if scanned == 0 || scanned >= total {
+		return found
+	}
+	return found * total / scanned
+}
+
+// emitEvent sends an event to the frontend
+func (a *App) emitEvent(name string, data interface{}) {
+	if a.emit != nil {
+		a.emit(name, data)
+		return
+	}
+	runtime.EventsEmit(a.ctx, name, data)
+}
+
+// StreamSearchFiles starts a search whose results are sent as they are found through "search:results"
+// events, followed by a "search:done" event. It returns the id carried by the events
+// a newer search or CancelSearch stops it without "search:done", options.Limit caps the number of results sent
+// an empty query is "empty_query", it would match every note
+func (a *App) StreamSearchFiles(rootPath string, query string, options SearchOptions) (int64, error) {
+	if strings.TrimSpace(query) == "" {
+		return 0, fmt.Errorf("empty_query")
+	}
+	rootPath, err := a.resolveVaultPath(rootPath)
+	if err != nil {
+		return 0, err
+	}
+	matcher, err := newSearchMatcher(query, options)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := a.startSearch()
+	id := a.searchStream.Add(1)
+	go func() {
+		batch := SearchBatch{ID: id, Results: []SearchResult{}}
+		lastFlush := time.Now()
+		flush := func() {
+			a.emitEvent("search:results", batch)
+			batch.Results = []SearchResult{}
+			lastFlush = time.Now()
+		}
+
+		truncated, err := a.scanSearch(ctx, rootPath, matcher, func(result *SearchResult, scanned, total int) bool {
+			batch.Scanned, batch.Total = scanned, total
+			if result != nil {
+				batch.Results = append(batch.Results, *result)
+				batch.Found++
+			}
+			batch.Estimate = estimateResults(batch.Found, scanned, total)
+
+			if options.Limit > 0 && batch.Found >= options.Limit {
+				return false
+			}
+			if len(batch.Results) >= searchBatchSize || (len(batch.Results) > 0 && time.Since(lastFlush) >= searchBatchInterval) {
+				flush()
+			}
+			return true
+		})
+
+		if err != nil {
+			return // superseded, the frontend listens to the newer search
+		}
+		if len(batch.Results) > 0 {
+			flush()
+		}
+		if batch.Scanned == batch.Total {
+			batch.Estimate = batch.Found
+		}
+		batch.Truncated = truncated
+		batch.Done = true
+		a.emitEvent("search:done", batch)
+	}()
+
+	return id, nil
+}
+
+// startSearch cancels the search in progress and returns the context of the new one
+func (a *App) startSearch() context.Context {
+	a.searchRun.mu.Lock()
+	defer a.searchRun.mu.Unlock()
+
+	if a.searchRun.cancel != nil {
+		a.searchRun.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a.searchRun.cancel = cancel
+	return ctx
+}
+
+// CancelSearch stops the search in progress, if any
+func (a *App) CancelSearch() {
+	a.searchRun.mu.Lock()
+	defer a.searchRun.mu.Unlock()
+
+	if a.searchRun.cancel != nil {
+		a.searchRun.cancel()
+		a.searchRun.cancel = nil
+	}
+}
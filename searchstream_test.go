@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchFilesPage(t *testing.T) {
+	a := newTestVault(t)
+	for i := 0; i < 75; i++ {
+		a.WriteContentInFile(filepath.Join(a.rootPath, fmt.Sprintf("note%02d.md", i)), "")
+	}
+
+	page, err := a.SearchFilesPage(a.rootPath, "note", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Results) != searchDefaultLimit || page.Total != 75 || !page.HasMore {
+		t.Fatalf("unexpected first page: %d results of %d", len(page.Results), page.Total)
+	}
+
+	page, _ = a.SearchFilesPage(a.rootPath, "note", SearchOptions{Offset: 70, Limit: 10})
+	if len(page.Results) != 5 || page.HasMore || page.Results[0].Name != "note70.md" {
+		t.Fatalf("unexpected last page %+v", page)
+	}
+
+	page, _ = a.SearchFilesPage(a.rootPath, "note", SearchOptions{Offset: 100})
+	if len(page.Results) != 0 || page.Offset != 75 {
+		t.Fatalf("a page past the end must be empty, got %+v", page)
+	}
+
+	page, _ = a.SearchFilesPage(a.rootPath, "  ", SearchOptions{})
+	if len(page.Results) != 0 {
+		t.Fatalf("a blank query must find nothing, got %d results", len(page.Results))
+	}
+}
+
+func TestStreamSearchFiles(t *testing.T) {
+	a := newTestVault(t)
+	for i := 0; i < 120; i++ {
+		a.WriteContentInFile(filepath.Join(a.rootPath, fmt.Sprintf("note%03d.md", i)), "some content")
+	}
+
+	events := make(chan SearchBatch, 100)
+	a.emit = func(name string, data interface{}) {
+		batch := data.(SearchBatch)
+		if batch.Done != (name == "search:done") {
+			t.Errorf("unexpected %s event %+v", name, batch)
+		}
+		events <- batch
+	}
+
+	id, err := a.StreamSearchFiles(a.rootPath, "content", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, batches := 0, 0
+	for {
+		select {
+		case batch := <-events:
+			if batch.ID != id {
+				t.Fatalf("unexpected search id %d", batch.ID)
+			}
+			if batch.Done {
+				if found != 120 || batch.Found != 120 || batch.Estimate != 120 || batches < 2 {
+					t.Fatalf("expected 120 results in several batches, got %d in %d batches: %+v", found, batches, batch)
+				}
+				return
+			}
+			found += len(batch.Results)
+			batches++
+		case <-time.After(5 * time.Second):
+			t.Fatal("the search never ended")
+		}
+	}
+}
+
+func TestSearchCancelledByNewerSearch(t *testing.T) {
+	a := newTestVault(t)
+	for i := 0; i < 20; i++ {
+		a.WriteContentInFile(filepath.Join(a.rootPath, fmt.Sprintf("note%02d.md", i)), "content")
+	}
+	matcher, _ := newSearchMatcher("content", SearchOptions{})
+
+	visited := 0
+	_, err := a.scanSearch(a.startSearch(), a.rootPath, matcher, func(result *SearchResult, _, _ int) bool {
+		if result != nil {
+			visited++
+			a.startSearch() // a newer query arrives
+		}
+		return true
+	})
+	if err == nil || err.Error() != "search_cancelled" || visited != 1 {
+		t.Fatalf("expected the search to stop once superseded, got %v after %d results", err, visited)
+	}
+
+	ctx := a.startSearch()
+	a.CancelSearch()
+	if ctx.Err() == nil {
+		t.Fatal("CancelSearch must cancel the search in progress")
+	}
+
+	a.emit = func(name string, data interface{}) {}
+	if _, err := a.StreamSearchFiles(a.rootPath, "(", SearchOptions{Regex: true}); err == nil {
+		t.Fatal("an invalid query must be reported right away")
+	}
+	for _, query := range []string{"", " \t"} {
+		if _, err := a.StreamSearchFiles(a.rootPath, query, SearchOptions{}); err == nil || err.Error() != "empty_query" {
+			t.Fatalf("%q: expected empty_query, got %v", query, err)
+		}
+	}
+}
+
+func TestScanSearchKeepsPathOrder(t *testing.T) {
+	a := newTestVault(t)
+	for i := 0; i < 200; i++ {
+		a.WriteContentInFile(filepath.Join(a.rootPath, fmt.Sprintf("note%03d.md", i)), strings.Repeat("content ", i))
+	}
+	matcher, _ := newSearchMatcher("content", SearchOptions{})
+
+	var paths []string
+	a.scanSearch(context.Background(), a.rootPath, matcher, func(result *SearchResult, _, _ int) bool {
+		if result != nil {
+			paths = append(paths, result.Path)
+		}
+		return true
+	})
+	if len(paths) != 199 || !sort.StringsAreSorted(paths) {
+		t.Fatalf("expected the 199 matching notes in path order, got %d", len(paths))
+	}
+
+	// checked before any name is matched or worker started
+	matcher, _ = newSearchMatcher(" ", SearchOptions{})
+	_, err := a.scanSearch(context.Background(), a.rootPath, matcher, func(result *SearchResult, _, _ int) bool {
+		t.Fatal("a blank query must not scan anything")
+		return false
+	})
+	if err == nil || err.Error() != "empty_query" {
+		t.Fatalf("expected empty_query, got %v", err)
+	}
+}
+
+func TestEstimateResults(t *testing.T) {
+	if estimate := estimateResults(10, 25, 100); estimate != 40 {
+		t.Fatalf("expected 40, got %d", estimate)
+	}
+	if estimate := estimateResults(10, 100, 100); estimate != 10 {
+		t.Fatalf("a finished scan must report what it found, got %d", estimate)
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	// inlineTagPattern matches #tags written in the content of a note
+	inlineTagPattern = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_/-]+)`)
+	tagPattern       = regexp.MustCompile(`^[\p{L}\p{N}_-]+(?:/[\p{L}\p{N}_-]+)*$`)
+	tagListPattern   = regexp.MustCompile(`[^\s,]+`) // tags of a "tags" property written as a string
+)
+
+type TagInfo struct {
+	Tag      string     `json:"tag"`                // eg: "project/tape"
+	Name     string     `json:"name"`               // last part of the tag, eg: "tape"
+	Count    int        `json:"count"`              // notes with the tag itself
+	Total    int        `json:"total"`              // notes with the tag or one of its subtags
+	Children []*TagInfo `json:"children,omitempty"` // subtags, eg: "project/tape" for "project"
+}
+
+// tagRef is an inline tag of a note, its byte range excludes the "#"
+type tagRef struct {
+	tag   string
+	start int
+	end   int
+}
+
+/**
+ * --- Tags
+ */
+// isTag reports if text is a valid tag: letters, digits, "_", "-" and "/" for subtags, not only digits
+func isTag(text string) bool {
+	return tagPattern.MatchString(text) && strings.IndexFunc(text, func(r rune) bool {
+		return !unicode.IsDigit(r)
+	}) != -1
+}
+
+// inlineTags returns the #tags written in a note, the ones in code, links and front matter are left out
+func inlineTags(content string) []tagRef {
+	var refs []tagRef
+	var regions []mdRegion
+
+	for _, match := range inlineTagPattern.FindAllStringSubmatchIndex(content, -1) {
+		start, end := match[2], match[3]
+		tag := strings.TrimRight(content[start:end], "/-") // punctuation ending a sentence, eg: "#todo-"
+		if !isTag(tag) {
+			continue // eg: "#123", an issue number
+		}
+		if regions == nil {
+			regions = markdownRegions(content)
+		}
+		if kind := regionAt(regions, start); kind == mdCode || kind == mdLink || kind == mdFrontmatter {
+			continue
+		}
+		refs = append(refs, tagRef{tag: tag, start: start, end: start + len(tag)})
+	}
+
+	return refs
+}
+
+// noteTags returns the lowercased tags of a note, from the front matter and #tags in the content
+func noteTags(content string) []string {
+	var tags []string
+	if meta := noteMeta(content); meta != nil {
+		tags = append(tags, meta.Tags...)
+	}
+	for _, ref := range inlineTags(content) {
+		tag := strings.ToLower(ref.tag)
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// normalizeTag lowercases a tag given by the user and drops its "#"
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// isTagOrSubtag reports if tag is parent or one of its subtags, both lowercased
+func isTagOrSubtag(tag, parent string) bool {
+	return tag == parent || strings.HasPrefix(tag, parent+"/")
+}
+
+// hasAnyTag reports if tags, lowercased, hold one of the wanted tags or one of their subtags
+func hasAnyTag(tags []string, wanted []string) bool {
+	for _, want := range wanted {
+		want = normalizeTag(want)
+		if slices.ContainsFunc(tags, func(tag string) bool { return isTagOrSubtag(tag, want) }) {
+			return true
+		}
+	}
+	return false
+}
+
+// tagDocs returns the notes of the vault with their tags, from the search index
+// the notes are read while it is not available, a locked vault has none
+func (a *App) tagDocs() ([]*indexedDoc, string) {
+	root, _ := filepath.Abs(a.rootPath)
+	if a.HasSecurity(root) && a.masterkey == nil {
+		return nil, root
+	}
+	if index := a.searchIndexFor(root); index != nil {
+		return index.allDocs(), root
+	}
+
+	docs := a.listVaultDocs(root)
+	for _, doc := range docs {
+		if doc.IsDir {
+			continue
+		}
+		content, err := a.ReadFile(filepath.Join(root, doc.Path))
+		if err != nil {
+			continue
+		}
+		doc.Meta = noteMeta(content)
+		doc.Tags = noteTags(content)
+	}
+	return docs, root
+}
+
+// ListTags returns the tags of the vault as a tree of subtags with the number of notes having them
+func (a *App) ListTags() []*TagInfo {
+	docs, _ := a.tagDocs()
+
+	tags := make(map[string]*TagInfo)
+	var get func(tag string) *TagInfo
+	get = func(tag string) *TagInfo {
+		if info, ok := tags[tag]; ok {
+			return info
+		}
+		info := &TagInfo{Tag: tag, Name: tag[strings.LastIndexByte(tag, '/')+1:]}
+		tags[tag] = info
+		if i := strings.LastIndexByte(tag, '/'); i != -1 {
+			parent := get(tag[:i])
+			parent.Children = append(parent.Children, info)
+		}
+		return info
+	}
+
+	for _, doc := range docs {
+		counted := make(map[string]bool) // a note counts once for a parent of several of its tags
+		for _, tag := range doc.Tags {
+			get(tag).Count++
+			for parent := tag; ; {
+				if !counted[parent] {
+					counted[parent] = true
+					get(parent).Total++
+				}
+				i := strings.LastIndexByte(parent, '/')
+				if i == -1 {
+					break
+				}
+				parent = parent[:i]
+			}
+		}
+	}
+
+	roots := []*TagInfo{}
+	for tag, info := range tags {
+		if !strings.Contains(tag, "/") {
+			roots = append(roots, info)
+		}
+		sortTags(info.Children)
+	}
+	sortTags(roots)
+
+	return roots
+}
+
+// sortTags sorts tags by name
+func sortTags(tags []*TagInfo) {
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Tag < tags[j].Tag
+	})
+}
+
+// NotesWithTag returns the notes having a tag or one of its subtags, sorted by name
+func (a *App) NotesWithTag(tag string) []FileItem {
+	tag = normalizeTag(tag)
+	docs, root := a.tagDocs()
+
+	notes := []FileItem{}
+	for _, doc := range docs {
+		if slices.ContainsFunc(doc.Tags, func(noteTag string) bool { return isTagOrSubtag(noteTag, tag) }) {
+			notes = append(notes, FileItem{Name: doc.Name, Path: filepath.Join(root, doc.Path), Meta: doc.Meta})
+		}
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		return strings.ToLower(notes[i].Name) < strings.ToLower(notes[j].Name)
+	})
+
+	return notes
+}
+
+// renameTag returns the content of a note with a tag and its subtags renamed, inline and in the front matter
+func renameTag(content, oldTag, newTag string) (string, int, error) {
+	count := 0
+	rename := func(tag string) (string, bool) {
+		lower := strings.ToLower(tag)
+		if !isTagOrSubtag(lower, oldTag) {
+			return tag, false
+		}
+		count++
+		return newTag + tag[len(oldTag):], true
+	}
+
+	// the front matter first, inline tags are found again after since it may change length
+	fm, err := parseFrontMatter(content)
+	if err != nil {
+		return "", 0, err
+	}
+	for _, property := range fm.properties() {
+		key := strings.ToLower(property.Key)
+		if key != "tags" && key != "tag" {
+			continue
+		}
+
+		var value interface{}
+		changed := false
+		switch tags := property.Value.(type) {
+		case []interface{}:
+			renamed := make([]interface{}, len(tags))
+			for i, item := range tags {
+				renamed[i] = item
+				if text, ok := item.(string); ok {
+					hash := strings.HasPrefix(text, "#")
+					if tag, ok := rename(strings.TrimPrefix(text, "#")); ok {
+						renamed[i], changed = tag, true
+						if hash {
+							renamed[i] = "#" + tag
+						}
+					}
+				}
+			}
+			value = renamed
+		case string:
+			value = tagListPattern.ReplaceAllStringFunc(tags, func(text string) string {
+				tag, ok := rename(strings.TrimPrefix(text, "#"))
+				if !ok {
+					return text
+				}
+				changed = true
+				if strings.HasPrefix(text, "#") {
+					return "#" + tag
+				}
+				return tag
+			})
+		}
+		if changed {
+			content, err = fm.set(content, property.Key, value, false)
+			if err != nil {
+				return "", 0, err
+			}
+			// the offsets of the lines after the property moved, eg: for "tag" after "tags"
+			fm, err = parseFrontMatter(content)
+			if err != nil {
+				return "", 0, err
+			}
+		}
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, ref := range inlineTags(content) {
+		tag, ok := rename(ref.tag)
+		if !ok {
+			continue
+		}
+		out.WriteString(content[last:ref.start])
+		out.WriteString(tag)
+		last = ref.end
+	}
+	out.WriteString(content[last:])
+
+	return out.String(), count, nil
+}
+
+// RenameTag renames a tag and its subtags in every note, eg: "#project/tape" becomes "#work/tape" for "project" => "work"
+// notes are written through WriteContentInFile, UndoReplace with the returned UndoID restores them,
+// also the ones already renamed when a note can't be written
+func (a *App) RenameTag(oldTag string, newTag string) (ReplaceResult, error) {
+	oldTag = normalizeTag(oldTag)
+	newTag = strings.TrimPrefix(strings.TrimSpace(newTag), "#")
+	if !isTag(oldTag) || !isTag(newTag) {
+		return ReplaceResult{}, fmt.Errorf("invalid_tag")
+	}
+
+	docs, root := a.tagDocs()
+	var changes []noteChange
+	for _, doc := range docs {
+		if !slices.ContainsFunc(doc.Tags, func(tag string) bool { return isTagOrSubtag(tag, oldTag) }) {
+			continue
+		}
+		path := filepath.Join(root, doc.Path)
+		content, err := a.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		renamed, count, err := renameTag(content, oldTag, newTag)
+		if err != nil || count == 0 || renamed == content {
+			continue // an invalid front matter is left as it is
+		}
+		changes = append(changes, noteChange{rel: doc.Path, name: doc.Name, before: content, after: renamed, replacements: count})
+	}
+
+	return a.applyNoteChanges(root, changes, nil)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInlineTags(t *testing.T) {
+	content := "---\ntags: [front]\nnote: \"#nottag\"\n---\n" +
+		"#todo and #project/tape, issue #123\n" +
+		"`#code` and [#link](#heading) a#b\n" +
+		"```\n#fenced\n```\n" +
+		"end with #done-\n"
+
+	var tags []string
+	for _, ref := range inlineTags(content) {
+		if content[ref.start:ref.end] != ref.tag {
+			t.Fatalf("the offsets of %q don't point to the tag", ref.tag)
+		}
+		tags = append(tags, ref.tag)
+	}
+	expected := []string{"todo", "project/tape", "done"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("expected %v, got %v", expected, tags)
+	}
+
+	tags = noteTags(content + "#TODO #Other\n")
+	expected = []string{"front", "todo", "project/tape", "done", "other"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("expected %v, got %v", expected, tags)
+	}
+}
+
+func TestListTags(t *testing.T) {
+	a := newTestVault(t)
+	a.WriteContentInFile(filepath.Join(a.rootPath, "a.md"), "#project/tape #project/site")
+	a.WriteContentInFile(filepath.Join(a.rootPath, "b.md"), "---\ntags: project\n---\n#idea")
+	a.WriteContentInFile(filepath.Join(a.rootPath, "c.md"), "#project/tape/ui")
+
+	tags := a.ListTags()
+	if len(tags) != 2 || tags[0].Tag != "idea" || tags[1].Tag != "project" {
+		t.Fatalf("unexpected tags %+v", tags)
+	}
+	project := tags[1]
+	if project.Count != 1 || project.Total != 3 || len(project.Children) != 2 {
+		t.Fatalf("expected a note with the tag and 3 with its subtags, got %+v", project)
+	}
+	tape := project.Children[1]
+	if tape.Tag != "project/tape" || tape.Name != "tape" || tape.Count != 1 || tape.Total != 2 {
+		t.Fatalf("unexpected subtag %+v", tape)
+	}
+	if len(tape.Children) != 1 || tape.Children[0].Tag != "project/tape/ui" {
+		t.Fatalf("unexpected subtags %+v", tape.Children)
+	}
+
+	notes := a.NotesWithTag("#Project/Tape")
+	if len(notes) != 2 || notes[0].Name != "a.md" || notes[1].Name != "c.md" {
+		t.Fatalf("expected the notes with the tag and its subtags, got %+v", notes)
+	}
+	if notes := a.NotesWithTag("proj"); len(notes) != 0 {
+		t.Fatalf("a tag must not match as a prefix, got %+v", notes)
+	}
+}
+
+func TestRenameTag(t *testing.T) {
+	a := newTestVault(t)
+	noteA := filepath.Join(a.rootPath, "a.md")
+	noteB := filepath.Join(a.rootPath, "b.md")
+	noteC := filepath.Join(a.rootPath, "c.md")
+	contentA := "---\ntags:\n  - project/tape\n  - other\n---\nSee #Project and #project/tape/ui, not #projects\n"
+	contentB := "---\ntags: \"#project, idea\"\n---\n`#project`\n"
+	a.WriteContentInFile(noteA, contentA)
+	a.WriteContentInFile(noteB, contentB)
+	a.WriteContentInFile(noteC, "#projects")
+
+	if _, err := a.RenameTag("project", "#12"); err == nil || err.Error() != "invalid_tag" {
+		t.Fatalf("expected invalid_tag, got %v", err)
+	}
+
+	result, err := a.RenameTag("#project", "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Files) != 2 || result.Replacements != 4 || result.UndoID == "" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	expected := "---\ntags:\n  - work/tape\n  - other\n---\nSee #work and #work/tape/ui, not #projects\n"
+	if content, _ := a.ReadFile(noteA); content != expected {
+		t.Fatalf("expected %q, got %q", expected, content)
+	}
+	expected = "---\ntags: '#work, idea'\n---\n`#project`\n"
+	if content, _ := a.ReadFile(noteB); content != expected {
+		t.Fatalf("expected %q, got %q", expected, content)
+	}
+	if content, _ := a.ReadFile(noteC); content != "#projects" {
+		t.Fatal("another tag starting with the same letters must not change")
+	}
+
+	if _, err := a.UndoReplace(result.UndoID); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := a.ReadFile(noteA); content != contentA {
+		t.Fatalf("expected the rename to be undone, got %q", content)
+	}
+	if content, _ := a.ReadFile(noteB); content != contentB {
+		t.Fatalf("expected the rename to be undone, got %q", content)
+	}
+}
+
+func TestRenameTagBothProperties(t *testing.T) {
+	// the first property changes the length of the front matter before the second one is set
+	content := "---\ntags:\n  - project\n  - other\ntag: project/ui\ntitle: Plan\n---\nbody\n"
+	renamed, count, err := renameTag(content, "project", "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "---\ntags:\n  - work\n  - other\ntag: work/ui\ntitle: Plan\n---\nbody\n"
+	if renamed != expected || count != 2 {
+		t.Fatalf("expected %q, got %q with %d renames", expected, renamed, count)
+	}
+}
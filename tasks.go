@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// taskPattern matches a task item of a list, the first group is the character of the checkbox
+	taskPattern         = regexp.MustCompile(`^[ \t]*(?:>[ \t]?)*(?:[-*+]|\d{1,9}[.)])[ \t]+\[([ xX])\](?:[ \t]+(.*))?$`)
+	taskDuePattern      = regexp.MustCompile(`(?:📅\x{FE0F}?[ \t]*|\bdue:)(\d{4}-\d{2}-\d{2})\b`)
+	taskPriorityPattern = regexp.MustCompile(`(🔺|⏫|🔼|🔽|⏬)\x{FE0F}?|\bpriority:(highest|high|medium|low|lowest)\b`)
+)
+
+// priorities of the emojis of a task, eg: "⏫ Pay the rent"
+var taskPriorities = map[string]string{
+	"🔺": "highest",
+	"⏫": "high",
+	"🔼": "medium",
+	"🔽": "low",
+	"⏬": "lowest",
+}
+
+type Task struct {
+	Path     string   `json:"path"`
+	Name     string   `json:"name"`     // display name of the note
+	Line     int      `json:"line"`     // 1-based
+	Text     string   `json:"text"`     // without the due date and the priority
+	Done     bool     `json:"done"`     // "- [x]"
+	Due      string   `json:"due"`      // "YYYY-MM-DD", empty without due date
+	Priority string   `json:"priority"` // "highest", "high", "medium", "low", "lowest" or empty
+	Tags     []string `json:"tags"`     // lowercased #tags of the task
+}
+
+type TaskFilter struct {
+	Status    string   `json:"status"`    // "open" or "done", every task when empty
+	Folder    string   `json:"folder"`    // only the notes of this folder and its subfolders
+	Tags      []string `json:"tags"`      // only the tasks with one of these tags or their subtags, on the task or in the front matter of its note
+	DueBefore string   `json:"dueBefore"` // "YYYY-MM-DD", only the tasks due on or before this day, eg: today for overdue tasks
+	Priority  string   `json:"priority"`  // only the tasks with this priority
+}
+
+// noteTask is a task of a note, box is the offset of the checkbox character
+type noteTask struct {
+	Task
+	box int
+}
+
+/**
+ * --- Tasks
+ */
+// parseTask returns the task of a line of a list, false when it isn't one
+func parseTask(text string) (Task, int, bool) {
+	match := taskPattern.FindStringSubmatchIndex(text)
+	if match == nil {
+		return Task{}, 0, false
+	}
+	task := Task{Done: text[match[2]] != ' ', Tags: []string{}}
+	if match[4] == -1 {
+		return task, match[2], true
+	}
+	body := text[match[4]:match[5]]
+
+	body = taskDuePattern.ReplaceAllStringFunc(body, func(due string) string {
+		date := taskDuePattern.FindStringSubmatch(due)[1]
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return due // eg: "due:2026-13-45" is kept in the text
+		}
+		task.Due = date
+		return ""
+	})
+	body = taskPriorityPattern.ReplaceAllStringFunc(body, func(priority string) string {
+		groups := taskPriorityPattern.FindStringSubmatch(priority)
+		task.Priority = groups[2]
+		if groups[1] != "" {
+			task.Priority = taskPriorities[groups[1]]
+		}
+		return ""
+	})
+	task.Text = strings.Join(strings.Fields(body), " ")
+
+	for _, ref := range inlineTags(task.Text) {
+		tag := strings.ToLower(ref.tag)
+		if !slices.Contains(task.Tags, tag) {
+			task.Tags = append(task.Tags, tag)
+		}
+	}
+
+	return task, match[2], true
+}
+
+// noteTasks returns the tasks of a note, the ones in code blocks and front matter are left out
+func noteTasks(content string) []noteTask {
+	var tasks []noteTask
+
+	lines := splitMarkdownLines(content)
+	first := 0
+	if end := frontmatterEnd(lines); end != -1 {
+		first = end + 1
+	}
+
+	var fenceChar byte
+	fenceLength := 0
+	for i := first; i < len(lines); i++ {
+		text := lines[i].text
+		if fenceLength > 0 {
+			if closesFence(text, fenceChar, fenceLength) {
+				fenceLength = 0
+			}
+			continue
+		}
+		if char, length := codeFence(text); length > 0 {
+			fenceChar, fenceLength = char, length
+			continue
+		}
+
+		task, box, ok := parseTask(text)
+		if !ok {
+			continue
+		}
+		task.Line = i + 1
+		tasks = append(tasks, noteTask{Task: task, box: lines[i].start + box})
+	}
+
+	return tasks
+}
+
+// matches reports if a task passes the filter, noteTags are the front matter tags of its note
+func (filter TaskFilter) matches(task Task, noteTags []string) bool {
+	if (filter.Status == "open" && task.Done) || (filter.Status == "done" && !task.Done) {
+		return false
+	}
+	if filter.DueBefore != "" && (task.Due == "" || task.Due > filter.DueBefore) {
+		return false
+	}
+	if filter.Priority != "" && task.Priority != filter.Priority {
+		return false
+	}
+	if len(filter.Tags) > 0 && !hasAnyTag(task.Tags, filter.Tags) && !hasAnyTag(noteTags, filter.Tags) {
+		return false
+	}
+	return true
+}
+
+// ListTasks returns the tasks of the notes of the vault passing the filter, sorted by note and line
+// names are the decrypted ones in privacy mode, a locked vault has no tasks
+func (a *App) ListTasks(filter TaskFilter) ([]Task, error) {
+	tasks := []Task{}
+	if filter.DueBefore != "" {
+		if _, err := time.Parse(time.DateOnly, filter.DueBefore); err != nil {
+			return tasks, fmt.Errorf("invalid_date")
+		}
+	}
+	root, err := a.resolveVaultPath(a.rootPath)
+	if err != nil {
+		return tasks, err
+	}
+	if a.HasSecurity(root) && a.masterkey == nil {
+		return tasks, nil
+	}
+
+	folder := ""
+	if filter.Folder != "" {
+		resolved, err := a.resolveVaultPath(filter.Folder)
+		if err != nil {
+			return tasks, err
+		}
+		folder, _ = filepath.Rel(root, resolved)
+	}
+
+	var docs []*indexedDoc
+	if index := a.searchIndexFor(root); index != nil {
+		docs = index.allDocs()
+	} else {
+		docs = a.listVaultDocs(root)
+	}
+	for _, doc := range docs {
+		if doc.IsDir || (folder != "" && folder != "." && !strings.HasPrefix(doc.Path, folder+string(filepath.Separator))) {
+			continue
+		}
+		path := filepath.Join(root, doc.Path)
+		content, err := a.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		var tags []string
+		if meta := noteMeta(content); meta != nil {
+			tags = meta.Tags
+		}
+		for _, task := range noteTasks(content) {
+			if !filter.matches(task.Task, tags) {
+				continue
+			}
+			task.Path, task.Name = path, doc.Name
+			tasks = append(tasks, task.Task)
+		}
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Path != tasks[j].Path {
+			return tasks[i].Path < tasks[j].Path
+		}
+		return tasks[i].Line < tasks[j].Line
+	})
+
+	return tasks, nil
+}
+
+// ToggleTask checks or unchecks the task at a line of a note, only its checkbox changes
+// the note is written through WriteContentInFile, a line without task is "invalid_task"
+func (a *App) ToggleTask(path string, line int) (Task, error) {
+	content, err := a.ReadFile(path)
+	if err != nil {
+		return Task{}, err
+	}
+
+	for _, task := range noteTasks(content) {
+		if task.Line != line {
+			continue
+		}
+		box := "x"
+		if task.Done {
+			box = " "
+		}
+		err = a.WriteContentInFile(path, content[:task.box]+box+content[task.box+1:])
+		if err != nil {
+			return Task{}, err
+		}
+		task.Done = !task.Done
+		task.Path, task.Name = path, a.displayName(path)
+		return task.Task, nil
+	}
+
+	return Task{}, fmt.Errorf("invalid_task")
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseTask(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected Task
+	}{
+		{"- [ ] Pay the rent 📅 2026-10-20 ⏫", Task{Text: "Pay the rent", Due: "2026-10-20", Priority: "high"}},
+		{"  * [x] Call #Work/Team due:2026-11-02 priority:low", Task{Text: "Call #Work/Team", Done: true, Due: "2026-11-02", Priority: "low"}},
+		{"1. [X] 🔽 Numbered", Task{Text: "Numbered", Done: true, Priority: "low"}},
+		{"> - [ ] Quoted due:2026-13-45", Task{Text: "Quoted due:2026-13-45"}},
+		{"- [ ]", Task{}},
+	}
+	for _, tt := range tests {
+		task, _, ok := parseTask(tt.line)
+		if !ok {
+			t.Fatalf("%q: expected a task", tt.line)
+		}
+		task.Tags = nil
+		if !reflect.DeepEqual(task, tt.expected) {
+			t.Fatalf("%q: expected %+v, got %+v", tt.line, tt.expected, task)
+		}
+	}
+
+	for _, line := range []string{"- [] not a task", "[ ] not a list", "-[ ] no space", "- [y] other"} {
+		if _, _, ok := parseTask(line); ok {
+			t.Fatalf("%q: expected no task", line)
+		}
+	}
+
+	task, _, _ := parseTask("- [ ] Call #Work/Team and #work/team")
+	if !reflect.DeepEqual(task.Tags, []string{"work/team"}) {
+		t.Fatalf("unexpected tags %v", task.Tags)
+	}
+}
+
+func TestListTasks(t *testing.T) {
+	a := newTestVault(t)
+	os.MkdirAll(filepath.Join(a.rootPath, "work"), 0755)
+	a.WriteContentInFile(filepath.Join(a.rootPath, "a.md"), "---\ntodo: - [ ] front\n---\n- [ ] Rent 📅 2026-10-20\n```\n- [ ] fenced\n```\n- [x] Done #home\n")
+	a.WriteContentInFile(filepath.Join(a.rootPath, "work", "b.md"), "---\ntags: work\n---\n- [ ] Report due:2026-11-01 ⏫\n")
+
+	tasks, err := a.ListTasks(TaskFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 3 || tasks[0].Line != 4 || tasks[1].Line != 8 || tasks[2].Name != "b.md" || tasks[2].Line != 4 {
+		t.Fatalf("unexpected tasks %+v", tasks)
+	}
+
+	filters := []struct {
+		filter   TaskFilter
+		expected []string
+	}{
+		{TaskFilter{Status: "open"}, []string{"Rent", "Report"}},
+		{TaskFilter{Status: "done"}, []string{"Done #home"}},
+		{TaskFilter{DueBefore: "2026-10-31"}, []string{"Rent"}},
+		{TaskFilter{Priority: "high"}, []string{"Report"}},
+		{TaskFilter{Tags: []string{"#home"}}, []string{"Done #home"}},
+		{TaskFilter{Tags: []string{"work"}}, []string{"Report"}}, // a tag of the front matter
+		{TaskFilter{Folder: filepath.Join(a.rootPath, "work")}, []string{"Report"}},
+	}
+	for _, tt := range filters {
+		tasks, err := a.ListTasks(tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var texts []string
+		for _, task := range tasks {
+			texts = append(texts, task.Text)
+		}
+		if !reflect.DeepEqual(texts, tt.expected) {
+			t.Fatalf("%+v: expected %v, got %v", tt.filter, tt.expected, texts)
+		}
+	}
+
+	if _, err := a.ListTasks(TaskFilter{DueBefore: "tomorrow"}); err == nil || err.Error() != "invalid_date" {
+		t.Fatalf("expected invalid_date, got %v", err)
+	}
+}
+
+func TestToggleTask(t *testing.T) {
+	a := newTestVault(t)
+	note := filepath.Join(a.rootPath, "a.md")
+	a.WriteContentInFile(note, "# Tasks\r\n- [ ] Rent\r\n- [X] Done\r\n")
+
+	task, err := a.ToggleTask(note, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !task.Done || task.Text != "Rent" || task.Name != "a.md" {
+		t.Fatalf("unexpected task %+v", task)
+	}
+	if _, err := a.ToggleTask(note, 3); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := a.ReadFile(note); content != "# Tasks\r\n- [x] Rent\r\n- [ ] Done\r\n" {
+		t.Fatalf("only the checkboxes must change, got %q", content)
+	}
+
+	if _, err := a.ToggleTask(note, 1); err == nil || err.Error() != "invalid_task" {
+		t.Fatalf("expected invalid_task, got %v", err)
+	}
+}